@@ -0,0 +1,25 @@
+package compress
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+type brotliCompressor struct{}
+
+func NewBrotli() Compressor {
+	return brotliCompressor{}
+}
+
+func (b brotliCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return brotli.NewWriter(w), nil
+}
+
+func (b brotliCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(brotli.NewReader(r)), nil
+}
+
+func (b brotliCompressor) GetEncoding() string {
+	return Brotli
+}
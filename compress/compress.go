@@ -0,0 +1,104 @@
+package compress
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	ContentEncoding = string
+)
+
+const (
+	Gzip     ContentEncoding = "gzip"
+	Deflate  ContentEncoding = "deflate"
+	Brotli   ContentEncoding = "br"
+	Zstd     ContentEncoding = "zstd"
+	Identity ContentEncoding = "identity"
+)
+
+type Compressor interface {
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+	GetEncoding() string
+}
+
+// Supported lists every encoding FromEncoding knows, in order of preference.
+func Supported() []string {
+	return []string{Gzip, Deflate, Brotli, Zstd}
+}
+
+// FromEncoding returns the compressor for a Content-Encoding token, the bool is
+// false when the encoding is unknown.
+func FromEncoding(encoding string) (Compressor, bool) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case Gzip, "x-gzip":
+		return NewGzip(), true
+	case Deflate:
+		return NewDeflate(), true
+	case Brotli:
+		return NewBrotli(), true
+	case Zstd:
+		return NewZstd(), true
+	default:
+		return nil, false
+	}
+}
+
+// Negotiate picks the encoding to respond with from an Accept-Encoding header.
+// The client's q-values win, ties go to the order of supported. An empty string
+// means the response should not be compressed.
+func Negotiate(acceptEncoding string, supported ...string) string {
+	if len(supported) == 0 {
+		supported = Supported()
+	}
+
+	weights := parseAcceptEncoding(acceptEncoding)
+
+	best, bestQ := "", 0.0
+
+	for _, encoding := range supported {
+		q, found := weights[encoding]
+		if !found {
+			q, found = weights["*"]
+		}
+
+		if found && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+
+	return best
+}
+
+func parseAcceptEncoding(acceptEncoding string) map[string]float64 {
+	weights := map[string]float64{}
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if len(name) == 0 {
+			continue
+		}
+
+		q := 1.0
+
+		for _, param := range params[1:] {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || strings.ToLower(key) != "q" {
+				continue
+			}
+
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err == nil {
+				q = parsed
+			}
+		}
+
+		weights[name] = q
+	}
+
+	return weights
+}
@@ -0,0 +1,96 @@
+package compress_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kevinanthony/gorps/v2/compress"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFromEncoding(t *testing.T) {
+	t.Parallel()
+
+	Convey("FromEncoding", t, func() {
+		Convey("should return compressor for", func() {
+			for _, encoding := range []string{"gzip", "x-gzip", "deflate", "br", "zstd", " GZIP "} {
+				comp, ok := compress.FromEncoding(encoding)
+
+				So(ok, ShouldBeTrue)
+				So(comp, ShouldNotBeNil)
+			}
+		})
+		Convey("should return false when encoding is unknown", func() {
+			comp, ok := compress.FromEncoding("compress")
+
+			So(ok, ShouldBeFalse)
+			So(comp, ShouldBeNil)
+		})
+	})
+}
+
+func TestCompressor_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	Convey("RoundTrip", t, func() {
+		expected := strings.Repeat("the quick brown fox jumps over the lazy dog ", 100)
+
+		for _, encoding := range compress.Supported() {
+			comp, ok := compress.FromEncoding(encoding)
+			So(ok, ShouldBeTrue)
+			So(comp.GetEncoding(), ShouldEqual, encoding)
+
+			var buf bytes.Buffer
+
+			w, err := comp.NewWriter(&buf)
+			So(err, ShouldBeNil)
+
+			_, err = w.Write([]byte(expected))
+			So(err, ShouldBeNil)
+			So(w.Close(), ShouldBeNil)
+			So(buf.Len(), ShouldBeLessThan, len(expected))
+
+			r, err := comp.NewReader(&buf)
+			So(err, ShouldBeNil)
+
+			actual, err := io.ReadAll(r)
+			So(err, ShouldBeNil)
+			So(r.Close(), ShouldBeNil)
+			So(string(actual), ShouldEqual, expected)
+		}
+	})
+}
+
+func TestNegotiate(t *testing.T) {
+	t.Parallel()
+
+	Convey("Negotiate", t, func() {
+		Convey("should return empty when", func() {
+			Convey("header is empty", func() {
+				So(compress.Negotiate(""), ShouldBeEmpty)
+			})
+			Convey("nothing is supported", func() {
+				So(compress.Negotiate("compress, identity"), ShouldBeEmpty)
+			})
+			Convey("everything is refused", func() {
+				So(compress.Negotiate("gzip;q=0, *;q=0"), ShouldBeEmpty)
+			})
+		})
+		Convey("should prefer server order on ties", func() {
+			So(compress.Negotiate("zstd, br, gzip"), ShouldEqual, compress.Gzip)
+		})
+		Convey("should prefer highest q-value", func() {
+			So(compress.Negotiate("gzip;q=0.5, br;q=0.9"), ShouldEqual, compress.Brotli)
+		})
+		Convey("should honor wildcard", func() {
+			So(compress.Negotiate("*"), ShouldEqual, compress.Gzip)
+			So(compress.Negotiate("gzip;q=0, *;q=0.1"), ShouldEqual, compress.Deflate)
+		})
+		Convey("should only pick from supported", func() {
+			So(compress.Negotiate("gzip, zstd", compress.Zstd), ShouldEqual, compress.Zstd)
+		})
+	})
+}
@@ -0,0 +1,25 @@
+package compress
+
+import (
+	"compress/zlib"
+	"io"
+)
+
+// deflateCompressor uses the zlib wrapper, which is what http means by deflate.
+type deflateCompressor struct{}
+
+func NewDeflate() Compressor {
+	return deflateCompressor{}
+}
+
+func (d deflateCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriter(w), nil
+}
+
+func (d deflateCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(r)
+}
+
+func (d deflateCompressor) GetEncoding() string {
+	return Deflate
+}
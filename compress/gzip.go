@@ -0,0 +1,24 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+type gzipCompressor struct{}
+
+func NewGzip() Compressor {
+	return gzipCompressor{}
+}
+
+func (g gzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (g gzipCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func (g gzipCompressor) GetEncoding() string {
+	return Gzip
+}
@@ -0,0 +1,30 @@
+package compress
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type zstdCompressor struct{}
+
+func NewZstd() Compressor {
+	return zstdCompressor{}
+}
+
+func (z zstdCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+}
+
+func (z zstdCompressor) NewReader(r io.Reader) (io.ReadCloser, error) {
+	dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+
+	return dec.IOReadCloser(), nil
+}
+
+func (z zstdCompressor) GetEncoding() string {
+	return Zstd
+}
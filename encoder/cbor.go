@@ -0,0 +1,39 @@
+package encoder
+
+import (
+	"github.com/fxamacker/cbor/v2"
+)
+
+type cborEncoder struct {
+	enc cbor.EncMode
+	dec cbor.DecMode
+}
+
+func NewCBOR() Encoder {
+	enc, err := cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+
+	dec, err := cbor.DecOptions{}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+
+	return cborEncoder{
+		enc: enc,
+		dec: dec,
+	}
+}
+
+func (c cborEncoder) Encode(data interface{}) ([]byte, error) {
+	return c.enc.Marshal(data)
+}
+
+func (c cborEncoder) Decode(data []byte, dst interface{}) error {
+	return c.dec.Unmarshal(data, dst)
+}
+
+func (c cborEncoder) GetMime() string {
+	return ApplicationCBOR
+}
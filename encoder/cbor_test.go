@@ -0,0 +1,95 @@
+package encoder_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewCBOR(t *testing.T) {
+	t.Parallel()
+
+	Convey("NewCBOR", t, func() {
+		Convey("should return type cborEncoder", func() {
+			actual := encoder.NewCBOR()
+
+			So(reflect.TypeOf(actual).String(), ShouldEqual, "encoder.cborEncoder")
+		})
+	})
+}
+
+func TestCBOREncoder_Encode(t *testing.T) {
+	t.Parallel()
+
+	Convey("Encode", t, func() {
+		enc := encoder.NewCBOR()
+
+		Convey("should return cbor bytes for structure", func() {
+			actual, err := enc.Encode(newTestStruct())
+
+			So(err, ShouldBeNil)
+			So(actual, ShouldNotBeEmpty)
+		})
+		Convey("should use json struct tags", func() {
+			actual, err := enc.Encode(subStrict{String: "else"})
+
+			So(err, ShouldBeNil)
+			So(string(actual), ShouldContainSubstring, "string")
+			So(string(actual), ShouldNotContainSubstring, "String")
+		})
+		Convey("should return error when", func() {
+			Convey("struct cannot be marshalled", func() {
+				actual, err := enc.Encode(badJSONStruct{F: func() {}})
+
+				So(actual, ShouldBeNil)
+				So(err, ShouldBeError, "cbor: unsupported type: encoder_test.badJSONStruct")
+			})
+		})
+	})
+}
+
+func TestCBOREncoder_Decode(t *testing.T) {
+	t.Parallel()
+
+	Convey("Decode", t, func() {
+		enc := encoder.NewCBOR()
+
+		expected := newTestStruct()
+		var actual testStruct
+
+		Convey("should round trip structure", func() {
+			bts, err := enc.Encode(expected)
+			So(err, ShouldBeNil)
+
+			err = enc.Decode(bts, &actual)
+
+			So(err, ShouldBeNil)
+			So(actual, ShouldResemble, expected)
+		})
+		Convey("should return error", func() {
+			Convey("when data is empty", func() {
+				err := enc.Decode(nil, &actual)
+
+				So(err, ShouldBeError, "EOF")
+			})
+			Convey("when out is not pointer", func() {
+				err := enc.Decode([]byte{0xa0}, actual)
+
+				So(err, ShouldBeError, "cbor: Unmarshal(non-pointer encoder_test.testStruct)")
+			})
+		})
+	})
+}
+
+func TestCBOREncoder_GetMime(t *testing.T) {
+	t.Parallel()
+
+	Convey("GetMime", t, func() {
+		enc := encoder.NewCBOR()
+
+		So(enc.GetMime(), ShouldEqual, "application/cbor")
+	})
+}
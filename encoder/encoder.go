@@ -1,13 +1,25 @@
 package encoder
 
+import "io"
+
 type (
 	AcceptType = string
 )
 
 const (
-	ApplicationJSON AcceptType = "application/json"
-	ApplicationXML  AcceptType = "application/xml"
-	TextXML         AcceptType = "text/xml"
+	ApplicationJSON        AcceptType = "application/json"
+	ApplicationXML         AcceptType = "application/xml"
+	TextXML                AcceptType = "text/xml"
+	ApplicationMsgPack     AcceptType = "application/msgpack"
+	ApplicationXMsgPack    AcceptType = "application/x-msgpack"
+	ApplicationCBOR        AcceptType = "application/cbor"
+	ApplicationYAML        AcceptType = "application/yaml"
+	ApplicationXYAML       AcceptType = "application/x-yaml"
+	TextYAML               AcceptType = "text/yaml"
+	ApplicationTOML        AcceptType = "application/toml"
+	TextPlain              AcceptType = "text/plain"
+	ApplicationOctetStream AcceptType = "application/octet-stream"
+	ApplicationNDJSON      AcceptType = "application/x-ndjson"
 )
 
 type Encoder interface {
@@ -15,3 +27,10 @@ type Encoder interface {
 	Decode(data []byte, dst interface{}) error
 	GetMime() string
 }
+
+// StreamEncoder is implemented by encoders that can write data as it is
+// produced instead of buffering the whole payload first.
+type StreamEncoder interface {
+	Encoder
+	EncodeTo(w io.Writer, data interface{}) error
+}
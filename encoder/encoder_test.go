@@ -5,20 +5,20 @@ import (
 )
 
 type testStruct struct {
-	Map    map[string]int `json:"map"    xml:"-"`
-	Slice  []string       `json:"slice"  xml:"slice"`
-	String string         `json:"string" xml:"string"`
-	Int    int            `json:"int"    xml:"int"`
-	Float  float32        `json:"float"  xml:"flat"`
-	Time   time.Time      `json:"time"   xml:"time"`
-	Struct subStrict      `json:"sub"    xml:"struct"`
+	Map    map[string]int `json:"map"    xml:"-"      yaml:"map"    toml:"map"`
+	Slice  []string       `json:"slice"  xml:"slice"  yaml:"slice"  toml:"slice"`
+	String string         `json:"string" xml:"string" yaml:"string" toml:"string"`
+	Int    int            `json:"int"    xml:"int"    yaml:"int"    toml:"int"`
+	Float  float32        `json:"float"  xml:"flat"   yaml:"float"  toml:"float"`
+	Time   time.Time      `json:"time"   xml:"time"   yaml:"time"   toml:"time"`
+	Struct subStrict      `json:"sub"    xml:"struct" yaml:"sub"    toml:"sub"`
 }
 
 type subStrict struct {
-	String string `json:"string" xml:"string"`
+	String string `json:"string" xml:"string" yaml:"string" toml:"string"`
 
-	Int   int     `json:"int"   xml:"int"`
-	Float float32 `json:"float" xml:"flat"`
+	Int   int     `json:"int"   xml:"int"  yaml:"int"   toml:"int"`
+	Float float32 `json:"float" xml:"flat" yaml:"float" toml:"float"`
 }
 
 func newTestStruct() testStruct {
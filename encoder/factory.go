@@ -13,10 +13,31 @@ type Factory interface {
 	FromMime(mediaType string) Encoder
 }
 
-type factory struct{}
+// FactoryOption configures the encoders handed out by NewFactory.
+type FactoryOption func(f *factory)
 
-func NewFactory() Factory {
-	return factory{}
+type factory struct {
+	json Encoder
+}
+
+func NewFactory(opts ...FactoryOption) Factory {
+	f := factory{
+		json: NewJSON(),
+	}
+
+	for _, opt := range opts {
+		opt(&f)
+	}
+
+	return f
+}
+
+// WithJSONOptions makes the factory return json encoders built with opts. The
+// encoder is built once and shared, so it is cheap to call FromMime per request.
+func WithJSONOptions(opts ...JSONOption) FactoryOption {
+	return func(f *factory) {
+		f.json = NewJSON(opts...)
+	}
 }
 
 func (f factory) CreateFromResponse(resp *http.Response) Encoder {
@@ -37,9 +58,23 @@ func (f factory) FromMime(mediaType string) Encoder {
 		return NewXML()
 	case ApplicationXML:
 		return NewXML()
+	case ApplicationMsgPack, ApplicationXMsgPack:
+		return NewMsgPack()
+	case ApplicationCBOR:
+		return NewCBOR()
+	case ApplicationYAML, ApplicationXYAML, TextYAML:
+		return NewYAML()
+	case ApplicationTOML:
+		return NewTOML()
+	case TextPlain:
+		return rawEncoder{mime: TextPlain, json: f.json}
+	case ApplicationOctetStream:
+		return rawEncoder{mime: ApplicationOctetStream, json: f.json}
+	case ApplicationNDJSON:
+		return NewNDJSON()
 	case ApplicationJSON:
-		return NewJSON()
+		return f.json
 	default:
-		return NewJSON()
+		return f.json
 	}
 }
@@ -21,6 +21,40 @@ func TestNewFactory(t *testing.T) {
 	})
 }
 
+func TestFactory_WithJSONOptions(t *testing.T) {
+	t.Parallel()
+
+	Convey("WithJSONOptions", t, func() {
+		factory := encoder.NewFactory(encoder.WithJSONOptions(encoder.JSONEscapeHTML(false)))
+
+		Convey("should return configured json encoder", func() {
+			Convey("when media type is application/json", func() {
+				actual, err := factory.FromMime(encoder.ApplicationJSON).Encode("<a>")
+
+				So(err, ShouldBeNil)
+				So(string(actual), ShouldEqual, `"<a>"`)
+			})
+			Convey("when media type is unknown", func() {
+				actual, err := factory.FromMime("").Encode("<a>")
+
+				So(err, ShouldBeNil)
+				So(string(actual), ShouldEqual, `"<a>"`)
+			})
+		})
+		Convey("should decode json sent as text/plain with the configured encoder", func() {
+			factory = encoder.NewFactory(encoder.WithJSONOptions(encoder.JSONDisallowUnknownFields()))
+
+			var actual struct {
+				A int `json:"a"`
+			}
+
+			So(factory.FromMime(encoder.TextPlain).Decode([]byte(`{"a":1}`), &actual), ShouldBeNil)
+			So(actual.A, ShouldEqual, 1)
+			So(factory.FromMime(encoder.TextPlain).Decode([]byte(`{"b":1}`), &actual), ShouldNotBeNil)
+		})
+	})
+}
+
 func TestFactoryMock_CreateFromResponse(t *testing.T) {
 	t.Parallel()
 
@@ -44,6 +78,88 @@ func TestFactoryMock_CreateFromResponse(t *testing.T) {
 				So(actual, ShouldHaveSameTypeAs, encoder.NewJSON())
 			})
 		})
+		Convey("should return msgpack encoder", func() {
+			Convey("when content-type is application/msgpack", func() {
+				resp.Header.Add("content-type", encoder.ApplicationMsgPack)
+
+				actual := factory.CreateFromResponse(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewMsgPack())
+			})
+			Convey("when content-type is application/x-msgpack", func() {
+				resp.Header.Add("content-type", encoder.ApplicationXMsgPack)
+
+				actual := factory.CreateFromResponse(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewMsgPack())
+			})
+		})
+		Convey("should return yaml encoder", func() {
+			Convey("when content-type is application/yaml", func() {
+				resp.Header.Add("content-type", encoder.ApplicationYAML)
+
+				actual := factory.CreateFromResponse(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewYAML())
+			})
+			Convey("when content-type is application/x-yaml", func() {
+				resp.Header.Add("content-type", encoder.ApplicationXYAML)
+
+				actual := factory.CreateFromResponse(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewYAML())
+			})
+			Convey("when content-type is text/yaml", func() {
+				resp.Header.Add("content-type", encoder.TextYAML)
+
+				actual := factory.CreateFromResponse(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewYAML())
+			})
+		})
+		Convey("should return toml encoder", func() {
+			Convey("when content-type is application/toml", func() {
+				resp.Header.Add("content-type", encoder.ApplicationTOML)
+
+				actual := factory.CreateFromResponse(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewTOML())
+			})
+		})
+		Convey("should return raw encoder", func() {
+			Convey("when content-type is text/plain", func() {
+				resp.Header.Add("content-type", "text/plain; charset=utf-8")
+
+				actual := factory.CreateFromResponse(resp)
+
+				So(actual.GetMime(), ShouldEqual, encoder.TextPlain)
+			})
+			Convey("when content-type is application/octet-stream", func() {
+				resp.Header.Add("content-type", encoder.ApplicationOctetStream)
+
+				actual := factory.CreateFromResponse(resp)
+
+				So(actual.GetMime(), ShouldEqual, encoder.ApplicationOctetStream)
+			})
+		})
+		Convey("should return ndjson encoder", func() {
+			Convey("when content-type is application/x-ndjson", func() {
+				resp.Header.Add("content-type", encoder.ApplicationNDJSON)
+
+				actual := factory.CreateFromResponse(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewNDJSON())
+			})
+		})
+		Convey("should return cbor encoder", func() {
+			Convey("when content-type is application/cbor", func() {
+				resp.Header.Add("content-type", encoder.ApplicationCBOR)
+
+				actual := factory.CreateFromResponse(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewCBOR())
+			})
+		})
 		Convey("should return xml encoder", func() {
 			Convey("when content-type is application/xml", func() {
 				resp.Header.Add("content-type", encoder.ApplicationXML)
@@ -79,6 +195,88 @@ func TestFactory_CreateFromRequest(t *testing.T) {
 				So(actual, ShouldHaveSameTypeAs, encoder.NewJSON())
 			})
 		})
+		Convey("should return msgpack encoder", func() {
+			Convey("when accept is application/msgpack", func() {
+				resp.Header.Add("accept", encoder.ApplicationMsgPack)
+
+				actual := factory.CreateFromRequest(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewMsgPack())
+			})
+			Convey("when accept is application/x-msgpack", func() {
+				resp.Header.Add("accept", encoder.ApplicationXMsgPack)
+
+				actual := factory.CreateFromRequest(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewMsgPack())
+			})
+		})
+		Convey("should return yaml encoder", func() {
+			Convey("when accept is application/yaml", func() {
+				resp.Header.Add("accept", encoder.ApplicationYAML)
+
+				actual := factory.CreateFromRequest(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewYAML())
+			})
+			Convey("when accept is application/x-yaml", func() {
+				resp.Header.Add("accept", encoder.ApplicationXYAML)
+
+				actual := factory.CreateFromRequest(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewYAML())
+			})
+			Convey("when accept is text/yaml", func() {
+				resp.Header.Add("accept", encoder.TextYAML)
+
+				actual := factory.CreateFromRequest(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewYAML())
+			})
+		})
+		Convey("should return toml encoder", func() {
+			Convey("when accept is application/toml", func() {
+				resp.Header.Add("accept", encoder.ApplicationTOML)
+
+				actual := factory.CreateFromRequest(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewTOML())
+			})
+		})
+		Convey("should return raw encoder", func() {
+			Convey("when accept is text/plain", func() {
+				resp.Header.Add("accept", "text/plain; charset=utf-8")
+
+				actual := factory.CreateFromRequest(resp)
+
+				So(actual.GetMime(), ShouldEqual, encoder.TextPlain)
+			})
+			Convey("when accept is application/octet-stream", func() {
+				resp.Header.Add("accept", encoder.ApplicationOctetStream)
+
+				actual := factory.CreateFromRequest(resp)
+
+				So(actual.GetMime(), ShouldEqual, encoder.ApplicationOctetStream)
+			})
+		})
+		Convey("should return ndjson encoder", func() {
+			Convey("when accept is application/x-ndjson", func() {
+				resp.Header.Add("accept", encoder.ApplicationNDJSON)
+
+				actual := factory.CreateFromRequest(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewNDJSON())
+			})
+		})
+		Convey("should return cbor encoder", func() {
+			Convey("when accept is application/cbor", func() {
+				resp.Header.Add("accept", encoder.ApplicationCBOR)
+
+				actual := factory.CreateFromRequest(resp)
+
+				So(actual, ShouldHaveSameTypeAs, encoder.NewCBOR())
+			})
+		})
 		Convey("should return xml encoder", func() {
 			Convey("when accept is application/xml", func() {
 				resp.Header.Add("accept", encoder.ApplicationXML)
@@ -4,13 +4,84 @@ import (
 	jit "github.com/json-iterator/go"
 )
 
+// JSONOption tweaks the json-iterator config used by NewJSON.
+type JSONOption func(cfg *jit.Config)
+
 type jsonEncoder struct {
 	jit jit.API
 }
 
-func NewJSON() Encoder {
+// NewJSON returns a json encoder that behaves like encoding/json unless options
+// are given. Options are applied in order, so JSONFastest should come first
+// when combined with others.
+func NewJSON(opts ...JSONOption) Encoder {
+	if len(opts) == 0 {
+		return jsonEncoder{
+			jit: jit.ConfigCompatibleWithStandardLibrary,
+		}
+	}
+
+	cfg := jit.Config{
+		EscapeHTML:             true,
+		SortMapKeys:            true,
+		ValidateJsonRawMessage: true,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	return jsonEncoder{
-		jit: jit.ConfigCompatibleWithStandardLibrary,
+		jit: cfg.Froze(),
+	}
+}
+
+// JSONUseNumber decodes numbers into interface{} values as json.Number instead
+// of float64, so large integers keep their precision.
+func JSONUseNumber() JSONOption {
+	return func(cfg *jit.Config) {
+		cfg.UseNumber = true
+	}
+}
+
+// JSONDisallowUnknownFields makes decoding fail when the payload has a field
+// the destination struct does not.
+func JSONDisallowUnknownFields() JSONOption {
+	return func(cfg *jit.Config) {
+		cfg.DisallowUnknownFields = true
+	}
+}
+
+// JSONCaseSensitive matches object keys to struct fields case sensitively.
+func JSONCaseSensitive() JSONOption {
+	return func(cfg *jit.Config) {
+		cfg.CaseSensitive = true
+	}
+}
+
+// JSONEscapeHTML turns escaping of <, > and & in strings on or off.
+func JSONEscapeHTML(escape bool) JSONOption {
+	return func(cfg *jit.Config) {
+		cfg.EscapeHTML = escape
+	}
+}
+
+// JSONIndent pretty prints output with the given number of spaces per level.
+func JSONIndent(spaces int) JSONOption {
+	return func(cfg *jit.Config) {
+		cfg.IndentionStep = spaces
+	}
+}
+
+// JSONFastest trades encoding/json compatibility for speed: no html escaping,
+// unsorted map keys and floats marshalled with 6 digits.
+func JSONFastest() JSONOption {
+	return func(cfg *jit.Config) {
+		cfg.EscapeHTML = false
+		cfg.SortMapKeys = false
+		cfg.ValidateJsonRawMessage = false
+		cfg.MarshalFloatWith6Digits = true
+		cfg.ObjectFieldMustBeSimpleString = true
 	}
 }
 
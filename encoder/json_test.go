@@ -1,6 +1,7 @@
 package encoder_test
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 
@@ -73,6 +74,69 @@ func TestJSONEncoder_Decode(t *testing.T) {
 	})
 }
 
+func TestJSONEncoder_Options(t *testing.T) {
+	t.Parallel()
+
+	Convey("Options", t, func() {
+		Convey("should use json.Number when UseNumber is set", func() {
+			enc := encoder.NewJSON(encoder.JSONUseNumber())
+
+			var actual map[string]interface{}
+			err := enc.Decode([]byte(`{"id":9007199254740993}`), &actual)
+
+			So(err, ShouldBeNil)
+			So(actual["id"], ShouldResemble, json.Number("9007199254740993"))
+		})
+		Convey("should reject unknown fields when DisallowUnknownFields is set", func() {
+			enc := encoder.NewJSON(encoder.JSONDisallowUnknownFields())
+
+			var actual subStrict
+			err := enc.Decode([]byte(`{"string":"else","nope":1}`), &actual)
+
+			So(err, ShouldBeError)
+			So(err.Error(), ShouldContainSubstring, "found unknown field: nope")
+		})
+		Convey("should match keys case sensitively when CaseSensitive is set", func() {
+			var actual subStrict
+
+			err := encoder.NewJSON().Decode([]byte(`{"STRING":"else"}`), &actual)
+			So(err, ShouldBeNil)
+			So(actual.String, ShouldEqual, "else")
+
+			actual = subStrict{}
+			err = encoder.NewJSON(encoder.JSONCaseSensitive()).Decode([]byte(`{"STRING":"else"}`), &actual)
+			So(err, ShouldBeNil)
+			So(actual.String, ShouldBeEmpty)
+		})
+		Convey("should toggle html escaping", func() {
+			escaped, err := encoder.NewJSON(encoder.JSONEscapeHTML(true)).Encode("<a>")
+			So(err, ShouldBeNil)
+			So(string(escaped), ShouldEqual, `"\u003ca\u003e"`)
+
+			raw, err := encoder.NewJSON(encoder.JSONEscapeHTML(false)).Encode("<a>")
+			So(err, ShouldBeNil)
+			So(string(raw), ShouldEqual, `"<a>"`)
+		})
+		Convey("should indent output", func() {
+			actual, err := encoder.NewJSON(encoder.JSONIndent(2)).Encode(subStrict{String: "else"})
+
+			So(err, ShouldBeNil)
+			So(string(actual), ShouldEqual, "{\n  \"string\": \"else\",\n  \"int\": 0,\n  \"float\": 0\n}")
+		})
+		Convey("should round trip in fastest mode", func() {
+			enc := encoder.NewJSON(encoder.JSONFastest())
+
+			bts, err := enc.Encode(subStrict{String: "<else>", Int: 99})
+			So(err, ShouldBeNil)
+			So(string(bts), ShouldEqual, `{"string":"<else>","int":99,"float":0}`)
+
+			var actual subStrict
+			So(enc.Decode(bts, &actual), ShouldBeNil)
+			So(actual, ShouldResemble, subStrict{String: "<else>", Int: 99})
+		})
+	})
+}
+
 func TestJsonEncoder_GetMime(t *testing.T) {
 	t.Parallel()
 
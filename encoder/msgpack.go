@@ -0,0 +1,39 @@
+package encoder
+
+import (
+	"bytes"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const msgpackStructTag = "json"
+
+type msgpackEncoder struct{}
+
+func NewMsgPack() Encoder {
+	return msgpackEncoder{}
+}
+
+func (m msgpackEncoder) Encode(data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag(msgpackStructTag)
+
+	if err := enc.Encode(data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (m msgpackEncoder) Decode(data []byte, dst interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag(msgpackStructTag)
+
+	return dec.Decode(dst)
+}
+
+func (m msgpackEncoder) GetMime() string {
+	return ApplicationMsgPack
+}
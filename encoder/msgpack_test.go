@@ -0,0 +1,100 @@
+package encoder_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewMsgPack(t *testing.T) {
+	t.Parallel()
+
+	Convey("NewMsgPack", t, func() {
+		Convey("should return type msgpackEncoder", func() {
+			actual := encoder.NewMsgPack()
+
+			So(reflect.TypeOf(actual).String(), ShouldEqual, "encoder.msgpackEncoder")
+		})
+	})
+}
+
+func TestMsgPackEncoder_Encode(t *testing.T) {
+	t.Parallel()
+
+	Convey("Encode", t, func() {
+		enc := encoder.NewMsgPack()
+
+		Convey("should return msgpack bytes for structure", func() {
+			actual, err := enc.Encode(newTestStruct())
+
+			So(err, ShouldBeNil)
+			So(actual, ShouldNotBeEmpty)
+		})
+		Convey("should use json struct tags", func() {
+			actual, err := enc.Encode(subStrict{String: "else"})
+
+			So(err, ShouldBeNil)
+			So(string(actual), ShouldContainSubstring, "string")
+			So(string(actual), ShouldNotContainSubstring, "String")
+		})
+		Convey("should return error when", func() {
+			Convey("struct cannot be marshalled", func() {
+				actual, err := enc.Encode(badJSONStruct{F: func() {}})
+
+				So(actual, ShouldBeNil)
+				So(err, ShouldBeError, "msgpack: Encode(unsupported func())")
+			})
+		})
+	})
+}
+
+func TestMsgPackEncoder_Decode(t *testing.T) {
+	t.Parallel()
+
+	Convey("Decode", t, func() {
+		enc := encoder.NewMsgPack()
+
+		expected := newTestStruct()
+		var actual testStruct
+
+		Convey("should round trip structure", func() {
+			bts, err := enc.Encode(expected)
+			So(err, ShouldBeNil)
+
+			err = enc.Decode(bts, &actual)
+
+			So(err, ShouldBeNil)
+			So(actual.Time.Equal(expected.Time), ShouldBeTrue)
+
+			// msgpack decodes time in the local timezone
+			actual.Time = expected.Time
+
+			So(actual, ShouldResemble, expected)
+		})
+		Convey("should return error", func() {
+			Convey("when data is empty", func() {
+				err := enc.Decode(nil, &actual)
+
+				So(err, ShouldBeError, "EOF")
+			})
+			Convey("when out is not pointer", func() {
+				err := enc.Decode(nil, actual)
+
+				So(err, ShouldBeError, "msgpack: Decode(non-pointer encoder_test.testStruct)")
+			})
+		})
+	})
+}
+
+func TestMsgPackEncoder_GetMime(t *testing.T) {
+	t.Parallel()
+
+	Convey("GetMime", t, func() {
+		enc := encoder.NewMsgPack()
+
+		So(enc.GetMime(), ShouldEqual, "application/msgpack")
+	})
+}
@@ -0,0 +1,158 @@
+package encoder
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+
+	jit "github.com/json-iterator/go"
+)
+
+type flusher interface {
+	Flush()
+}
+
+type ndjsonEncoder struct {
+	jit jit.API
+}
+
+// NewNDJSON returns an encoder for newline delimited json. Encoding accepts a
+// slice, a channel, or a range function (func(yield func(T) bool)) and writes
+// one json document per value. When writing fails a channel is drained until
+// its producer closes it, and a range function sees yield return false.
+// Decoding appends every line to a slice.
+func NewNDJSON() StreamEncoder {
+	return ndjsonEncoder{
+		jit: jit.ConfigCompatibleWithStandardLibrary,
+	}
+}
+
+func (n ndjsonEncoder) Encode(data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := n.EncodeTo(&buf, data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (n ndjsonEncoder) EncodeTo(w io.Writer, data interface{}) error {
+	writeLine := func(item interface{}) error {
+		bts, err := n.jit.Marshal(item)
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(append(bts, '\n')); err != nil {
+			return err
+		}
+
+		if f, ok := w.(flusher); ok {
+			f.Flush()
+		}
+
+		return nil
+	}
+
+	value := reflect.ValueOf(data)
+
+	//nolint: exhaustive
+	switch value.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := writeLine(value.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case reflect.Chan:
+		for {
+			item, ok := value.Recv()
+			if !ok {
+				return nil
+			}
+
+			if err := writeLine(item.Interface()); err != nil {
+				// keep receiving so the producer is not stuck on its next send
+				go drain(value)
+
+				return err
+			}
+		}
+	case reflect.Func:
+		return n.encodeRangeFunc(value, writeLine)
+	default:
+		return writeLine(data)
+	}
+}
+
+// drain receives from the channel until it is closed.
+func drain(ch reflect.Value) {
+	for {
+		if _, ok := ch.Recv(); !ok {
+			return
+		}
+	}
+}
+
+func (n ndjsonEncoder) encodeRangeFunc(value reflect.Value, writeLine func(interface{}) error) error {
+	typeOf := value.Type()
+	if typeOf.NumIn() != 1 || typeOf.NumOut() != 0 {
+		return fmt.Errorf("%s: unsupported func type %s", ApplicationNDJSON, typeOf)
+	}
+
+	yieldType := typeOf.In(0)
+	if yieldType.Kind() != reflect.Func || yieldType.NumIn() != 1 ||
+		yieldType.NumOut() != 1 || yieldType.Out(0).Kind() != reflect.Bool {
+		return fmt.Errorf("%s: unsupported func type %s", ApplicationNDJSON, typeOf)
+	}
+
+	var err error
+
+	yield := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+		err = writeLine(args[0].Interface())
+
+		return []reflect.Value{reflect.ValueOf(err == nil)}
+	})
+
+	value.Call([]reflect.Value{yield})
+
+	return err
+}
+
+func (n ndjsonEncoder) Decode(data []byte, dst interface{}) error {
+	value := reflect.ValueOf(dst)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("%s: can only decode into pointer to slice, got %T", ApplicationNDJSON, dst)
+	}
+
+	slice := value.Elem()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		item := reflect.New(slice.Type().Elem())
+		if err := n.jit.Unmarshal(line, item.Interface()); err != nil {
+			return err
+		}
+
+		slice.Set(reflect.Append(slice, item.Elem()))
+	}
+
+	return scanner.Err()
+}
+
+func (n ndjsonEncoder) GetMime() string {
+	return ApplicationNDJSON
+}
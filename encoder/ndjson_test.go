@@ -0,0 +1,209 @@
+package encoder_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewNDJSON(t *testing.T) {
+	t.Parallel()
+
+	Convey("NewNDJSON", t, func() {
+		Convey("should return type ndjsonEncoder", func() {
+			actual := encoder.NewNDJSON()
+
+			So(reflect.TypeOf(actual).String(), ShouldEqual, "encoder.ndjsonEncoder")
+		})
+	})
+}
+
+func TestNDJSONEncoder_Encode(t *testing.T) {
+	t.Parallel()
+
+	Convey("Encode", t, func() {
+		enc := encoder.NewNDJSON()
+		expected := ndjsonString()
+
+		Convey("should write one line per value when data is", func() {
+			Convey("a slice", func() {
+				actual, err := enc.Encode([]subStrict{{String: "one", Int: 1}, {String: "two", Int: 2}})
+
+				So(err, ShouldBeNil)
+				So(string(actual), ShouldEqual, expected)
+			})
+			Convey("a channel", func() {
+				ch := make(chan subStrict, 2)
+				ch <- subStrict{String: "one", Int: 1}
+				ch <- subStrict{String: "two", Int: 2}
+				close(ch)
+
+				actual, err := enc.Encode(ch)
+
+				So(err, ShouldBeNil)
+				So(string(actual), ShouldEqual, expected)
+			})
+			Convey("a range function", func() {
+				seq := func(yield func(subStrict) bool) {
+					_ = yield(subStrict{String: "one", Int: 1}) &&
+						yield(subStrict{String: "two", Int: 2})
+				}
+
+				actual, err := enc.Encode(seq)
+
+				So(err, ShouldBeNil)
+				So(string(actual), ShouldEqual, expected)
+			})
+			Convey("a single value", func() {
+				actual, err := enc.Encode(subStrict{String: "one", Int: 1})
+
+				So(err, ShouldBeNil)
+				So(string(actual), ShouldEqual, `{"string":"one","int":1,"float":0}`+"\n")
+			})
+			Convey("nil", func() {
+				actual, err := enc.Encode(nil)
+
+				So(err, ShouldBeNil)
+				So(actual, ShouldBeEmpty)
+			})
+		})
+		Convey("should return error when", func() {
+			Convey("a value cannot be marshalled", func() {
+				actual, err := enc.Encode([]badJSONStruct{{F: func() {}}})
+
+				So(actual, ShouldBeNil)
+				So(err, ShouldBeError, "encoder_test.badJSONStruct.F:  Ffunc() is unsupported type")
+			})
+			Convey("a range function value cannot be marshalled", func() {
+				called := 0
+				seq := func(yield func(badJSONStruct) bool) {
+					for yield(badJSONStruct{F: func() {}}) {
+						called++
+					}
+				}
+
+				actual, err := enc.Encode(seq)
+
+				So(actual, ShouldBeNil)
+				So(err, ShouldBeError)
+				So(called, ShouldEqual, 0)
+			})
+			Convey("func is not a range function", func() {
+				actual, err := enc.Encode(func() {})
+
+				So(actual, ShouldBeNil)
+				So(err, ShouldBeError, "application/x-ndjson: unsupported func type func()")
+			})
+			Convey("writer fails", func() {
+				err := enc.EncodeTo(badWriter{}, []int{1})
+
+				So(err, ShouldBeError, "bad writer")
+			})
+		})
+	})
+}
+
+func TestNDJSONEncoder_EncodeTo(t *testing.T) {
+	t.Parallel()
+
+	Convey("EncodeTo", t, func() {
+		enc := encoder.NewNDJSON()
+
+		Convey("should flush after every line", func() {
+			w := &flushWriter{}
+
+			err := enc.EncodeTo(w, []int{1, 2, 3})
+
+			So(err, ShouldBeNil)
+			So(w.String(), ShouldEqual, "1\n2\n3\n")
+			So(w.flushed, ShouldEqual, 3)
+		})
+		Convey("should drain a channel after the writer fails", func() {
+			ch := make(chan int)
+			done := make(chan struct{})
+
+			go func() {
+				defer close(done)
+				defer close(ch)
+
+				for i := 0; i < 3; i++ {
+					ch <- i
+				}
+			}()
+
+			err := enc.EncodeTo(badWriter{}, ch)
+
+			So(err, ShouldBeError, "bad writer")
+			So(func() { <-done }, ShouldNotPanic)
+		})
+	})
+}
+
+func TestNDJSONEncoder_Decode(t *testing.T) {
+	t.Parallel()
+
+	Convey("Decode", t, func() {
+		enc := encoder.NewNDJSON()
+
+		Convey("should append every line to slice", func() {
+			var actual []subStrict
+
+			err := enc.Decode([]byte(ndjsonString()+"\n\n"), &actual)
+
+			So(err, ShouldBeNil)
+			So(actual, ShouldResemble, []subStrict{{String: "one", Int: 1}, {String: "two", Int: 2}})
+		})
+		Convey("should return error", func() {
+			Convey("when out is not a pointer to slice", func() {
+				var actual subStrict
+
+				err := enc.Decode([]byte(ndjsonString()), &actual)
+
+				So(err, ShouldBeError, "application/x-ndjson: can only decode into pointer to slice, got *encoder_test.subStrict")
+			})
+			Convey("when a line is not valid json", func() {
+				var actual []subStrict
+
+				err := enc.Decode([]byte("{\n"), &actual)
+
+				So(err, ShouldBeError)
+			})
+		})
+	})
+}
+
+func TestNDJSONEncoder_GetMime(t *testing.T) {
+	t.Parallel()
+
+	Convey("GetMime", t, func() {
+		enc := encoder.NewNDJSON()
+
+		So(enc.GetMime(), ShouldEqual, "application/x-ndjson")
+	})
+}
+
+func ndjsonString() string {
+	return `{"string":"one","int":1,"float":0}
+{"string":"two","int":2,"float":0}
+`
+}
+
+type badWriter struct{}
+
+func (badWriter) Write([]byte) (int, error) {
+	return 0, errors.New("bad writer")
+}
+
+type flushWriter struct {
+	bytes.Buffer
+	flushed int
+}
+
+func (f *flushWriter) Flush() {
+	f.flushed++
+}
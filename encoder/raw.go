@@ -0,0 +1,57 @@
+package encoder
+
+import (
+	"fmt"
+	"io"
+)
+
+// rawEncoder passes bytes through untouched, it backs both text/plain and
+// application/octet-stream. Anything other than a string or bytes is decoded
+// with the json encoder, as servers that don't set a Content-Type send their
+// json as text/plain.
+type rawEncoder struct {
+	mime string
+	json Encoder
+}
+
+func NewText() Encoder {
+	return rawEncoder{mime: TextPlain, json: NewJSON()}
+}
+
+func NewOctetStream() Encoder {
+	return rawEncoder{mime: ApplicationOctetStream, json: NewJSON()}
+}
+
+func (r rawEncoder) Encode(data interface{}) ([]byte, error) {
+	switch src := data.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(src), nil
+	case []byte:
+		return src, nil
+	case io.Reader:
+		return io.ReadAll(src)
+	case fmt.Stringer:
+		return []byte(src.String()), nil
+	default:
+		return nil, fmt.Errorf("%s: cannot encode type %T", r.mime, data)
+	}
+}
+
+func (r rawEncoder) Decode(data []byte, dst interface{}) error {
+	switch out := dst.(type) {
+	case *string:
+		*out = string(data)
+	case *[]byte:
+		*out = append([]byte{}, data...)
+	default:
+		return r.json.Decode(data, dst)
+	}
+
+	return nil
+}
+
+func (r rawEncoder) GetMime() string {
+	return r.mime
+}
@@ -0,0 +1,149 @@
+package encoder_test
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewText(t *testing.T) {
+	t.Parallel()
+
+	Convey("NewText", t, func() {
+		Convey("should return type rawEncoder", func() {
+			actual := encoder.NewText()
+
+			So(reflect.TypeOf(actual).String(), ShouldEqual, "encoder.rawEncoder")
+		})
+	})
+}
+
+func TestNewOctetStream(t *testing.T) {
+	t.Parallel()
+
+	Convey("NewOctetStream", t, func() {
+		Convey("should return type rawEncoder", func() {
+			actual := encoder.NewOctetStream()
+
+			So(reflect.TypeOf(actual).String(), ShouldEqual, "encoder.rawEncoder")
+		})
+	})
+}
+
+func TestRawEncoder_Encode(t *testing.T) {
+	t.Parallel()
+
+	Convey("Encode", t, func() {
+		enc := encoder.NewText()
+
+		Convey("should write data verbatim when it is", func() {
+			Convey("nil", func() {
+				actual, err := enc.Encode(nil)
+
+				So(err, ShouldBeNil)
+				So(actual, ShouldBeEmpty)
+			})
+			Convey("a string", func() {
+				actual, err := enc.Encode("you are a teapot")
+
+				So(err, ShouldBeNil)
+				So(string(actual), ShouldEqual, "you are a teapot")
+			})
+			Convey("a byte slice", func() {
+				actual, err := enc.Encode([]byte{0x00, 0xff})
+
+				So(err, ShouldBeNil)
+				So(actual, ShouldResemble, []byte{0x00, 0xff})
+			})
+			Convey("an io.Reader", func() {
+				actual, err := enc.Encode(strings.NewReader("from a reader"))
+
+				So(err, ShouldBeNil)
+				So(string(actual), ShouldEqual, "from a reader")
+			})
+			Convey("a fmt.Stringer", func() {
+				actual, err := enc.Encode(net.IPv4(127, 0, 0, 1))
+
+				So(err, ShouldBeNil)
+				So(string(actual), ShouldEqual, "127.0.0.1")
+			})
+		})
+		Convey("should return error when", func() {
+			Convey("type is not supported", func() {
+				actual, err := enc.Encode(newTestStruct())
+
+				So(actual, ShouldBeNil)
+				So(err, ShouldBeError, "text/plain: cannot encode type encoder_test.testStruct")
+			})
+			Convey("reader fails", func() {
+				actual, err := enc.Encode(badReader{})
+
+				So(actual, ShouldBeEmpty)
+				So(err, ShouldBeError, "bad reader")
+			})
+		})
+	})
+}
+
+func TestRawEncoder_Decode(t *testing.T) {
+	t.Parallel()
+
+	Convey("Decode", t, func() {
+		enc := encoder.NewOctetStream()
+
+		Convey("should decode into string", func() {
+			var actual string
+
+			err := enc.Decode([]byte("something"), &actual)
+
+			So(err, ShouldBeNil)
+			So(actual, ShouldEqual, "something")
+		})
+		Convey("should decode into a copy of the bytes", func() {
+			var actual []byte
+			data := []byte{0x01, 0x02}
+
+			err := enc.Decode(data, &actual)
+			data[0] = 0x00
+
+			So(err, ShouldBeNil)
+			So(actual, ShouldResemble, []byte{0x01, 0x02})
+		})
+		Convey("should decode other types as json", func() {
+			var actual map[string]int
+
+			err := encoder.NewText().Decode([]byte(`{"a":1}`), &actual)
+
+			So(err, ShouldBeNil)
+			So(actual, ShouldResemble, map[string]int{"a": 1})
+		})
+		Convey("should return error when other types are not json", func() {
+			var actual testStruct
+
+			err := enc.Decode([]byte("something"), &actual)
+
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestRawEncoder_GetMime(t *testing.T) {
+	t.Parallel()
+
+	Convey("GetMime", t, func() {
+		So(encoder.NewText().GetMime(), ShouldEqual, "text/plain")
+		So(encoder.NewOctetStream().GetMime(), ShouldEqual, "application/octet-stream")
+	})
+}
+
+type badReader struct{}
+
+func (badReader) Read([]byte) (int, error) {
+	return 0, errors.New("bad reader")
+}
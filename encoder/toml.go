@@ -0,0 +1,31 @@
+package encoder
+
+import (
+	"bytes"
+
+	"github.com/BurntSushi/toml"
+)
+
+type tomlEncoder struct{}
+
+func NewTOML() Encoder {
+	return tomlEncoder{}
+}
+
+func (t tomlEncoder) Encode(data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := toml.NewEncoder(&buf).Encode(data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (t tomlEncoder) Decode(data []byte, dst interface{}) error {
+	return toml.Unmarshal(data, dst)
+}
+
+func (t tomlEncoder) GetMime() string {
+	return ApplicationTOML
+}
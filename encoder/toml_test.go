@@ -0,0 +1,103 @@
+package encoder_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewTOML(t *testing.T) {
+	t.Parallel()
+
+	Convey("NewTOML", t, func() {
+		Convey("should return type tomlEncoder", func() {
+			actual := encoder.NewTOML()
+
+			So(reflect.TypeOf(actual).String(), ShouldEqual, "encoder.tomlEncoder")
+		})
+	})
+}
+
+func TestTOMLEncoder_Encode(t *testing.T) {
+	t.Parallel()
+
+	Convey("Encode", t, func() {
+		enc := encoder.NewTOML()
+
+		Convey("should return toml string for structure", func() {
+			actual, err := enc.Encode(newTestStruct())
+
+			So(err, ShouldBeNil)
+			So(string(actual), ShouldEqual, tomlString())
+		})
+		Convey("should return error when", func() {
+			Convey("struct cannot be marshalled", func() {
+				actual, err := enc.Encode(badXMLStruct{M: map[int]int{1: 1}})
+
+				So(actual, ShouldBeNil)
+				So(err, ShouldBeError, "toml: cannot encode a map with non-string key type")
+			})
+		})
+	})
+}
+
+func TestTOMLEncoder_Decode(t *testing.T) {
+	t.Parallel()
+
+	Convey("Decode", t, func() {
+		enc := encoder.NewTOML()
+
+		var actual testStruct
+
+		Convey("should return structure for toml string", func() {
+			err := enc.Decode([]byte(tomlString()), &actual)
+
+			So(err, ShouldBeNil)
+			So(actual, ShouldResemble, newTestStruct())
+		})
+		Convey("should return error", func() {
+			Convey("when data is not valid toml", func() {
+				err := enc.Decode([]byte("a: [:"), &actual)
+
+				So(err, ShouldBeError, "toml: line 1: expected '.' or '=', but got ':' instead")
+			})
+			Convey("when out is not pointer", func() {
+				err := enc.Decode(nil, actual)
+
+				So(err, ShouldBeError, `toml: cannot decode to non-pointer "encoder_test.testStruct"`)
+			})
+		})
+	})
+}
+
+func TestTOMLEncoder_GetMime(t *testing.T) {
+	t.Parallel()
+
+	Convey("GetMime", t, func() {
+		enc := encoder.NewTOML()
+
+		So(enc.GetMime(), ShouldEqual, "application/toml")
+	})
+}
+
+func tomlString() string {
+	return `slice = ["one", "two", "ah-ha-ha"]
+string = "something"
+int = 42
+float = 3.1415
+time = 1989-11-09T18:01:00Z
+
+[map]
+  1 = 1
+  2 = 2
+  3 = 3
+
+[sub]
+  string = "else"
+  int = 99
+  float = 2.7182
+`
+}
@@ -0,0 +1,32 @@
+package encoder
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+type yamlEncoder struct{}
+
+func NewYAML() Encoder {
+	return yamlEncoder{}
+}
+
+func (y yamlEncoder) Encode(data interface{}) (bts []byte, err error) {
+	// yaml.v3 panics on types it cannot marshal instead of returning an error
+	defer func() {
+		if r := recover(); r != nil {
+			bts, err = nil, fmt.Errorf("yaml: %v", r)
+		}
+	}()
+
+	return yaml.Marshal(data)
+}
+
+func (y yamlEncoder) Decode(data []byte, dst interface{}) error {
+	return yaml.Unmarshal(data, dst)
+}
+
+func (y yamlEncoder) GetMime() string {
+	return ApplicationYAML
+}
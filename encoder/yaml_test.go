@@ -0,0 +1,104 @@
+package encoder_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewYAML(t *testing.T) {
+	t.Parallel()
+
+	Convey("NewYAML", t, func() {
+		Convey("should return type yamlEncoder", func() {
+			actual := encoder.NewYAML()
+
+			So(reflect.TypeOf(actual).String(), ShouldEqual, "encoder.yamlEncoder")
+		})
+	})
+}
+
+func TestYAMLEncoder_Encode(t *testing.T) {
+	t.Parallel()
+
+	Convey("Encode", t, func() {
+		enc := encoder.NewYAML()
+
+		Convey("should return yaml string for structure", func() {
+			actual, err := enc.Encode(newTestStruct())
+
+			So(err, ShouldBeNil)
+			So(string(actual), ShouldEqual, yamlString())
+		})
+		Convey("should return error when", func() {
+			Convey("struct cannot be marshalled", func() {
+				actual, err := enc.Encode(badJSONStruct{F: func() {}})
+
+				So(actual, ShouldBeNil)
+				So(err, ShouldBeError, "yaml: cannot marshal type: func()")
+			})
+		})
+	})
+}
+
+func TestYAMLEncoder_Decode(t *testing.T) {
+	t.Parallel()
+
+	Convey("Decode", t, func() {
+		enc := encoder.NewYAML()
+
+		var actual testStruct
+
+		Convey("should return structure for yaml string", func() {
+			err := enc.Decode([]byte(yamlString()), &actual)
+
+			So(err, ShouldBeNil)
+			So(actual, ShouldResemble, newTestStruct())
+		})
+		Convey("should return error", func() {
+			Convey("when data is not valid yaml", func() {
+				err := enc.Decode([]byte("a: [:"), &actual)
+
+				So(err, ShouldBeError, "yaml: did not find expected node content")
+			})
+			Convey("when data does not match type", func() {
+				err := enc.Decode([]byte("x"), &actual)
+
+				So(err, ShouldBeError, "yaml: unmarshal errors:\n  line 1: cannot unmarshal !!str `x` into encoder_test.testStruct")
+			})
+		})
+	})
+}
+
+func TestYAMLEncoder_GetMime(t *testing.T) {
+	t.Parallel()
+
+	Convey("GetMime", t, func() {
+		enc := encoder.NewYAML()
+
+		So(enc.GetMime(), ShouldEqual, "application/yaml")
+	})
+}
+
+func yamlString() string {
+	return `map:
+    "1": 1
+    "2": 2
+    "3": 3
+slice:
+    - one
+    - two
+    - ah-ha-ha
+string: something
+int: 42
+float: 3.1415
+time: 1989-11-09T18:01:00Z
+sub:
+    string: else
+    int: 99
+    float: 2.7182
+`
+}
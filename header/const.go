@@ -1,7 +1,28 @@
 package header
 
 const (
-	Accept        = "Accept"
-	ContentType   = "Content-Type"
-	ContentLength = "Content-Length"
+	Accept          = "Accept"
+	AcceptEncoding  = "Accept-Encoding"
+	Authorization   = "Authorization"
+	ContentType     = "Content-Type"
+	ContentLength   = "Content-Length"
+	ContentEncoding = "Content-Encoding"
+	Vary            = "Vary"
+)
+
+const (
+	Age               = "Age"
+	CacheControl      = "Cache-Control"
+	ContentLocation   = "Content-Location"
+	Date              = "Date"
+	ETag              = "ETag"
+	Expires           = "Expires"
+	IfMatch           = "If-Match"
+	IfModifiedSince   = "If-Modified-Since"
+	IfNoneMatch       = "If-None-Match"
+	IfRange           = "If-Range"
+	IfUnmodifiedSince = "If-Unmodified-Since"
+	LastModified      = "Last-Modified"
+	Location          = "Location"
+	Range             = "Range"
 )
@@ -0,0 +1,247 @@
+package http
+
+import (
+	"context"
+	"io"
+	native "net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/header"
+
+	"github.com/pkg/errors"
+)
+
+// tokenLeeway renews a token this long before it expires, so it doesn't run
+// out while a request is in flight.
+const tokenLeeway = 10 * time.Second
+
+// Authenticator adds credentials to a request right before it is sent.
+//
+//go:generate mockery --name=Authenticator --structname=AuthenticatorMock --filename=authenticator_mock.go --inpackage
+type Authenticator interface {
+	Authenticate(req *native.Request) error
+}
+
+// Refresher is implemented by authenticators whose credentials can go stale.
+// When a request is answered with 401 the Client calls Invalidate and retries
+// it once with fresh credentials.
+type Refresher interface {
+	Invalidate()
+}
+
+// AuthenticatorFunc lets an ordinary function be used as an Authenticator.
+type AuthenticatorFunc func(req *native.Request) error
+
+func (f AuthenticatorFunc) Authenticate(req *native.Request) error {
+	return f(req)
+}
+
+// BasicAuth authenticates with a username and password (RFC 7617).
+func BasicAuth(username, password string) Authenticator {
+	return AuthenticatorFunc(func(req *native.Request) error {
+		req.SetBasicAuth(username, password)
+
+		return nil
+	})
+}
+
+// BearerToken authenticates with a static bearer token (RFC 6750).
+func BearerToken(token string) Authenticator {
+	return AuthenticatorFunc(func(req *native.Request) error {
+		req.Header.Set(header.Authorization, "Bearer "+token)
+
+		return nil
+	})
+}
+
+// APIKeyHeader sends key in the header name.
+func APIKeyHeader(name, key string) Authenticator {
+	return AuthenticatorFunc(func(req *native.Request) error {
+		req.Header.Set(name, key)
+
+		return nil
+	})
+}
+
+// APIKeyQuery sends key as the query parameter name.
+func APIKeyQuery(name, key string) Authenticator {
+	return AuthenticatorFunc(func(req *native.Request) error {
+		query := req.URL.Query()
+		query.Set(name, key)
+		req.URL.RawQuery = query.Encode()
+
+		return nil
+	})
+}
+
+// ClientCredentials authenticates with bearer tokens from an OAuth2 token
+// endpoint using the client credentials grant (RFC 6749 section 4.4). Tokens
+// are cached until they expire and fetched again after a 401.
+type ClientCredentials struct {
+	client       Native
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func NewClientCredentials(client Native, tokenURL, clientID, clientSecret string, scopes ...string) *ClientCredentials {
+	if client == nil {
+		panic("http client is required")
+	}
+
+	return &ClientCredentials{
+		client:       client,
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		scopes:       scopes,
+	}
+}
+
+func (c *ClientCredentials) Authenticate(req *native.Request) error {
+	token, err := c.Token(req.Context())
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(header.Authorization, "Bearer "+token)
+
+	return nil
+}
+
+// Invalidate drops the cached token, the next request fetches a new one.
+func (c *ClientCredentials) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = ""
+}
+
+// Token returns the cached token, fetching a new one when there is none or it
+// is about to expire.
+func (c *ClientCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.token) > 0 && (c.expires.IsZero() || time.Now().Before(c.expires)) {
+		return c.token, nil
+	}
+
+	token, err := c.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c.token = token.AccessToken
+	c.expires = time.Time{}
+
+	if token.ExpiresIn > 0 {
+		c.expires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - tokenLeeway)
+	}
+
+	return c.token, nil
+}
+
+func (c *ClientCredentials) fetch(ctx context.Context) (*tokenResponse, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.scopes) > 0 {
+		form.Set("scope", strings.Join(c.scopes, " "))
+	}
+
+	req, err := native.NewRequestWithContext(ctx, native.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.Wrap(err, "token endpoint")
+	}
+
+	req.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret))
+	req.Header.Set(header.ContentType, "application/x-www-form-urlencoded")
+	req.Header.Set(header.Accept, encoder.ApplicationJSON)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "token endpoint")
+	}
+
+	defer resp.Body.Close()
+
+	bts, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "token endpoint")
+	}
+
+	if resp.StatusCode >= native.StatusBadRequest {
+		return nil, errors.Errorf("token endpoint: %d: %s", resp.StatusCode, strings.TrimSpace(string(bts)))
+	}
+
+	var token tokenResponse
+	if err := encoder.NewJSON().Decode(bts, &token); err != nil {
+		return nil, errors.Wrap(err, "token endpoint")
+	}
+
+	if len(token.AccessToken) == 0 {
+		return nil, errors.New("token endpoint: no access_token in response")
+	}
+
+	return &token, nil
+}
+
+type authKey struct{}
+
+// withAuth stores the authenticator of a single request for the Client to
+// apply.
+func withAuth(ctx context.Context, auth Authenticator) context.Context {
+	return context.WithValue(ctx, authKey{}, auth)
+}
+
+func authFrom(ctx context.Context, fallback Authenticator) Authenticator {
+	if auth, ok := ctx.Value(authKey{}).(Authenticator); ok {
+		return auth
+	}
+
+	return fallback
+}
+
+// ownCredentials reports whether req was given its own Auth or Sign, which the
+// Client applies only after the cache and coalescing, so their responses must
+// not be shared with other requests.
+func ownCredentials(req *native.Request) bool {
+	return authFrom(req.Context(), nil) != nil || signerFrom(req.Context(), nil) != nil
+}
+
+// replayable copies req for a retry, it fails when the body can't be read
+// again.
+func replayable(req *native.Request) (*native.Request, bool) {
+	retry := req.Clone(req.Context())
+
+	if req.Body == nil || req.Body == native.NoBody {
+		return retry, true
+	}
+
+	if req.GetBody == nil {
+		return nil, false
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+
+	retry.Body = body
+
+	return retry, true
+}
@@ -0,0 +1,225 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	native "net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/http"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuth(t *testing.T) {
+	t.Parallel()
+
+	Convey("Auth", t, func() {
+		ctx := context.Background()
+		nativeMock := &http.NativeMock{}
+
+		authenticated := func(auth http.Authenticator, check func(req *native.Request) bool) {
+			nativeMock.On("Do", mock.MatchedBy(check)).Return(newResponse(native.StatusOK, nil), nil).Once()
+
+			c := http.NewClient(nativeMock, encoder.NewFactory(), http.WithAuth(auth))
+
+			So(http.NewRequest(c).URL("https://test.com/?a=1").DoAndUnmarshal(ctx, &struct{}{}), ShouldBeNil)
+			mock.AssertExpectationsForObjects(t, nativeMock)
+		}
+
+		Convey("BasicAuth should set basic credentials", func() {
+			authenticated(http.BasicAuth("user", "pass"), func(req *native.Request) bool {
+				user, pass, ok := req.BasicAuth()
+
+				return ok && user == "user" && pass == "pass"
+			})
+		})
+		Convey("BearerToken should set bearer token", func() {
+			authenticated(http.BearerToken("token"), func(req *native.Request) bool {
+				return req.Header.Get("Authorization") == "Bearer token"
+			})
+		})
+		Convey("APIKeyHeader should set the header", func() {
+			authenticated(http.APIKeyHeader("X-Api-Key", "key"), func(req *native.Request) bool {
+				return req.Header.Get("X-Api-Key") == "key"
+			})
+		})
+		Convey("APIKeyQuery should add the query parameter", func() {
+			authenticated(http.APIKeyQuery("api_key", "key"), func(req *native.Request) bool {
+				return req.URL.RawQuery == "a=1&api_key=key"
+			})
+		})
+		Convey("RequestBroker Auth should override the client default", func() {
+			nativeMock.On("Do", mock.MatchedBy(func(req *native.Request) bool {
+				return req.Header.Get("Authorization") == "Bearer request"
+			})).Return(newResponse(native.StatusOK, nil), nil).Once()
+
+			c := http.NewClient(nativeMock, encoder.NewFactory(), http.WithAuth(http.BearerToken("client")))
+
+			err := http.NewRequest(c).URL("https://test.com").Auth(http.BearerToken("request")).DoAndUnmarshal(ctx, &struct{}{})
+
+			So(err, ShouldBeNil)
+			mock.AssertExpectationsForObjects(t, nativeMock)
+		})
+		Convey("should not retry a 401 when credentials can't be refreshed", func() {
+			nativeMock.On("Do", mock.Anything).Return(newResponse(native.StatusUnauthorized, "denied"), nil).Once()
+
+			c := http.NewClient(nativeMock, encoder.NewFactory(), http.WithAuth(http.BearerToken("token")))
+
+			err := http.NewRequest(c).URL("https://test.com").DoAndUnmarshal(ctx, &struct{}{})
+
+			So(err, ShouldBeError, "401: denied: bad requestBroker")
+			mock.AssertExpectationsForObjects(t, nativeMock)
+		})
+		Convey("should return error when authenticating fails", func() {
+			authMock := &http.AuthenticatorMock{}
+			authMock.On("Authenticate", mock.Anything).Return(errors.New("no credentials")).Once()
+
+			c := http.NewClient(nativeMock, encoder.NewFactory(), http.WithAuth(authMock))
+
+			err := http.NewRequest(c).URL("https://test.com").DoAndUnmarshal(ctx, &struct{}{})
+
+			So(err, ShouldBeError, "authenticate: no credentials")
+			nativeMock.AssertNotCalled(t, "Do", mock.Anything)
+		})
+		Convey("should return error when authenticator is nil", func() {
+			_, err := http.NewRequest(&http.ClientMock{}).URL("https://test.com").Auth(nil).CreateRequest(ctx)
+
+			So(err, ShouldBeError, "authenticator is nil")
+		})
+	})
+}
+
+func TestClientCredentials(t *testing.T) {
+	t.Parallel()
+
+	Convey("ClientCredentials", t, func() {
+		ctx := context.Background()
+
+		var (
+			fetches   atomic.Int32
+			expiresIn atomic.Int32
+		)
+
+		expiresIn.Store(3600)
+
+		tokens := httptest.NewServer(native.HandlerFunc(func(w native.ResponseWriter, r *native.Request) {
+			id, secret, _ := r.BasicAuth()
+			if id != "id" || secret != "secret" || r.FormValue("grant_type") != "client_credentials" {
+				w.WriteHeader(native.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+
+				return
+			}
+
+			n := fetches.Add(1)
+			w.Header().Set("Content-Type", encoder.ApplicationJSON)
+			_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d,"scope":%q}`,
+				n, expiresIn.Load(), r.FormValue("scope"))
+		}))
+
+		// the api accepts only the latest token, so older ones get a 401
+		api := httptest.NewServer(native.HandlerFunc(func(w native.ResponseWriter, r *native.Request) {
+			if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token-%d", fetches.Load()) {
+				w.WriteHeader(native.StatusUnauthorized)
+
+				return
+			}
+
+			w.Header().Set("Content-Type", encoder.ApplicationJSON)
+			_, _ = fmt.Fprintf(w, `{"auth":%q}`, r.Header.Get("Authorization"))
+		}))
+
+		Reset(func() {
+			tokens.Close()
+			api.Close()
+		})
+
+		creds := http.NewClientCredentials(tokens.Client(), tokens.URL, "id", "secret", "read", "write")
+		c := http.NewClient(api.Client(), encoder.NewFactory(), http.WithAuth(creds))
+
+		call := func() (string, error) {
+			var out struct {
+				Auth string `json:"auth"`
+			}
+
+			err := http.NewRequest(c).URL(api.URL).DoAndUnmarshal(ctx, &out)
+
+			return out.Auth, err
+		}
+
+		Convey("should fetch a token once and reuse it", func() {
+			for i := 0; i < 3; i++ {
+				auth, err := call()
+
+				So(err, ShouldBeNil)
+				So(auth, ShouldEqual, "Bearer token-1")
+			}
+
+			So(fetches.Load(), ShouldEqual, 1)
+		})
+		Convey("should fetch a new token when the cached one expired", func() {
+			expiresIn.Store(1)
+
+			_, _ = call()
+			_, _ = call()
+
+			So(fetches.Load(), ShouldEqual, 2)
+		})
+		Convey("should refresh the token and retry after a 401", func() {
+			token, err := creds.Token(ctx)
+			So(err, ShouldBeNil)
+			So(token, ShouldEqual, "token-1")
+
+			// another client rotates the token, so ours is now rejected
+			fetches.Add(1)
+
+			auth, err := call()
+
+			So(err, ShouldBeNil)
+			So(auth, ShouldEqual, "Bearer token-3")
+		})
+		Convey("should replay the body when retrying", func() {
+			_, _ = creds.Token(ctx)
+			fetches.Add(1)
+
+			err := http.NewRequest(c).Post().URL(api.URL).JSON(map[string]int{"a": 1}).DoAndUnmarshal(ctx, &struct{}{})
+
+			So(err, ShouldBeNil)
+		})
+		Convey("should fetch a single token for concurrent requests", func() {
+			wg := sync.WaitGroup{}
+
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					_, _ = call()
+				}()
+			}
+
+			wg.Wait()
+
+			So(fetches.Load(), ShouldEqual, 1)
+		})
+		Convey("should return error when the token endpoint refuses", func() {
+			bad := http.NewClientCredentials(tokens.Client(), tokens.URL, "id", "wrong")
+			c := http.NewClient(api.Client(), encoder.NewFactory(), http.WithAuth(bad))
+
+			err := http.NewRequest(c).URL(api.URL).DoAndUnmarshal(ctx, &struct{}{})
+
+			So(err, ShouldBeError, `authenticate: token endpoint: 401: {"error":"invalid_client"}`)
+		})
+		Convey("should panic when client is nil", func() {
+			So(func() { http.NewClientCredentials(nil, tokens.URL, "id", "secret") }, ShouldPanicWith, "http client is required")
+		})
+	})
+}
@@ -0,0 +1,293 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	native "net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultFailureRatio     = 0.5
+	defaultMinRequests      = 10
+	defaultBreakerWindow    = time.Minute
+	defaultBreakerCooldown  = 30 * time.Second
+	defaultHalfOpenRequests = 1
+)
+
+// ErrCircuitOpen matches every CircuitOpenError with errors.Is.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// BreakerState is the state of the circuit of a host.
+type BreakerState int
+
+const (
+	// StateClosed lets every request through.
+	StateClosed BreakerState = iota
+	// StateOpen refuses every request until the cooldown has passed.
+	StateOpen
+	// StateHalfOpen lets a few trial requests through, their outcome closes or
+	// opens the circuit again.
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// CircuitOpenError is returned instead of sending a request to a host whose
+// circuit is open.
+type CircuitOpenError struct {
+	Host  string
+	State BreakerState
+	// RetryAt is when the circuit lets a trial request through again.
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit %s for %s", e.State, e.Host)
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// BreakerOption configures a CircuitBreaker.
+type BreakerOption func(b *CircuitBreaker)
+
+// CircuitBreaker stops sending requests to a host once too many of them fail,
+// keeping a separate circuit per host.
+type CircuitBreaker struct {
+	ratio       float64
+	minRequests int
+	window      time.Duration
+	cooldown    time.Duration
+	halfOpen    int
+	now         func() time.Time
+	isFailure   func(resp *native.Response, err error) bool
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	state       BreakerState
+	openedAt    time.Time
+	windowStart time.Time
+	requests    int
+	failures    int
+	trials      int
+	successes   int
+}
+
+func NewCircuitBreaker(opts ...BreakerOption) *CircuitBreaker {
+	b := &CircuitBreaker{
+		ratio:       defaultFailureRatio,
+		minRequests: defaultMinRequests,
+		window:      defaultBreakerWindow,
+		cooldown:    defaultBreakerCooldown,
+		halfOpen:    defaultHalfOpenRequests,
+		now:         time.Now,
+		isFailure:   isServerFailure,
+		circuits:    map[string]*circuit{},
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// WithFailureRatio opens the circuit once ratio of the requests in the window
+// failed, as long as there were at least minRequests of them.
+func WithFailureRatio(ratio float64, minRequests int) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.ratio = ratio
+		b.minRequests = minRequests
+	}
+}
+
+// WithBreakerWindow sets how long requests are counted before the counts of a
+// closed circuit start over.
+func WithBreakerWindow(d time.Duration) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.window = d
+	}
+}
+
+// WithCooldown sets how long a circuit stays open before trial requests are
+// let through.
+func WithCooldown(d time.Duration) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.cooldown = d
+	}
+}
+
+// WithHalfOpenRequests sets how many trial requests must succeed to close a
+// half-open circuit.
+func WithHalfOpenRequests(n int) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.halfOpen = n
+	}
+}
+
+// WithBreakerClock replaces time.Now, so tests can move time forward.
+func WithBreakerClock(now func() time.Time) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.now = now
+	}
+}
+
+// WithFailureFunc decides which outcomes count as failures, by default errors
+// other than a canceled context and 5xx responses do.
+func WithFailureFunc(isFailure func(resp *native.Response, err error) bool) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.isFailure = isFailure
+	}
+}
+
+// State returns the state of the circuit for host.
+func (b *CircuitBreaker) State(host string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[host]
+	if !ok {
+		return StateClosed
+	}
+
+	return b.state(c)
+}
+
+// States returns the state of every host seen so far, e.g. for health checks.
+func (b *CircuitBreaker) States() map[string]BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	states := make(map[string]BreakerState, len(b.circuits))
+	for host, c := range b.circuits {
+		states[host] = b.state(c)
+	}
+
+	return states
+}
+
+// allow reports whether a request to host may be sent.
+func (b *CircuitBreaker) allow(host string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(host)
+
+	switch b.state(c) {
+	case StateOpen:
+		return &CircuitOpenError{Host: host, State: StateOpen, RetryAt: c.openedAt.Add(b.cooldown)}
+	case StateHalfOpen:
+		if c.state == StateOpen {
+			c.state = StateHalfOpen
+			c.trials = 0
+			c.successes = 0
+		}
+
+		if c.trials >= b.halfOpen {
+			return &CircuitOpenError{Host: host, State: StateHalfOpen, RetryAt: b.now()}
+		}
+
+		c.trials++
+	case StateClosed:
+	}
+
+	return nil
+}
+
+// record counts the outcome of a request allowed to host.
+func (b *CircuitBreaker) record(host string, resp *native.Response, err error) {
+	failed := b.isFailure(resp, err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(host)
+	now := b.now()
+
+	switch c.state {
+	case StateHalfOpen:
+		if errors.Is(err, context.Canceled) {
+			// a trial that was given up on says nothing about the host, so
+			// its slot goes to the next request
+			c.trials--
+
+			return
+		}
+
+		if failed {
+			b.open(c, now)
+
+			return
+		}
+
+		if c.successes++; c.successes >= b.halfOpen {
+			*c = circuit{state: StateClosed, windowStart: now}
+		}
+	case StateClosed:
+		if now.Sub(c.windowStart) >= b.window {
+			*c = circuit{state: StateClosed, windowStart: now}
+		}
+
+		c.requests++
+		if failed {
+			c.failures++
+		}
+
+		if c.requests >= b.minRequests && float64(c.failures) >= b.ratio*float64(c.requests) {
+			b.open(c, now)
+		}
+	case StateOpen:
+		// the request was let through before the circuit opened
+	}
+}
+
+func (b *CircuitBreaker) open(c *circuit, now time.Time) {
+	*c = circuit{state: StateOpen, openedAt: now}
+}
+
+// state is the state of c at the current time, an open circuit whose cooldown
+// has passed is half-open.
+func (b *CircuitBreaker) state(c *circuit) BreakerState {
+	if c.state == StateOpen && !b.now().Before(c.openedAt.Add(b.cooldown)) {
+		return StateHalfOpen
+	}
+
+	return c.state
+}
+
+func (b *CircuitBreaker) circuit(host string) *circuit {
+	c, ok := b.circuits[host]
+	if !ok {
+		c = &circuit{state: StateClosed, windowStart: b.now()}
+		b.circuits[host] = c
+	}
+
+	return c
+}
+
+func isServerFailure(resp *native.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+
+	return resp.StatusCode >= native.StatusInternalServerError
+}
@@ -0,0 +1,179 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	native "net/http"
+	"testing"
+	"time"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/http"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	Convey("CircuitBreaker", t, func() {
+		ctx := context.Background()
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		status := native.StatusOK
+		sent := 0
+
+		var sendErr error
+
+		nativeMock := &http.NativeMock{}
+		nativeMock.On("Do", mock.Anything).Return(func(*native.Request) (*native.Response, error) {
+			sent++
+
+			if sendErr != nil {
+				return nil, sendErr
+			}
+
+			return newResponse(status, nil), nil
+		})
+
+		breaker := http.NewCircuitBreaker(
+			http.WithFailureRatio(0.5, 4),
+			http.WithCooldown(10*time.Second),
+			http.WithBreakerWindow(time.Minute),
+			http.WithHalfOpenRequests(2),
+			http.WithBreakerClock(func() time.Time { return now }),
+		)
+		c := http.NewClient(nativeMock, encoder.NewFactory(), http.WithCircuitBreaker(breaker))
+
+		call := func(host string) error {
+			return http.NewRequest(c).URL("https://"+host).DoAndUnmarshal(ctx, &struct{}{})
+		}
+		trip := func() {
+			status = native.StatusBadGateway
+
+			for i := 0; i < 4; i++ {
+				So(call("a.com"), ShouldBeError, "502: : bad requestBroker")
+			}
+		}
+
+		Convey("should stay closed while requests succeed", func() {
+			for i := 0; i < 10; i++ {
+				So(call("a.com"), ShouldBeNil)
+			}
+
+			So(breaker.State("a.com"), ShouldEqual, http.StateClosed)
+		})
+		Convey("should stay closed below the failure ratio", func() {
+			for i := 0; i < 6; i++ {
+				status = native.StatusOK
+				if i%3 == 2 {
+					status = native.StatusInternalServerError
+				}
+
+				_ = call("a.com")
+			}
+
+			So(breaker.State("a.com"), ShouldEqual, http.StateClosed)
+		})
+		Convey("should not count client errors as failures", func() {
+			status = native.StatusNotFound
+
+			for i := 0; i < 10; i++ {
+				_ = call("a.com")
+			}
+
+			So(breaker.State("a.com"), ShouldEqual, http.StateClosed)
+		})
+		Convey("should start counting again after the window", func() {
+			status = native.StatusBadGateway
+
+			for i := 0; i < 3; i++ {
+				_ = call("a.com")
+			}
+
+			now = now.Add(time.Minute)
+			_ = call("a.com")
+
+			So(breaker.State("a.com"), ShouldEqual, http.StateClosed)
+		})
+		Convey("when too many requests fail", func() {
+			trip()
+
+			Convey("should open and short circuit", func() {
+				err := call("a.com")
+
+				var openErr *http.CircuitOpenError
+
+				So(errors.As(err, &openErr), ShouldBeTrue)
+				So(errors.Is(err, http.ErrCircuitOpen), ShouldBeTrue)
+				So(err, ShouldBeError, "circuit open for a.com")
+				So(openErr.RetryAt, ShouldEqual, now.Add(10*time.Second))
+				So(sent, ShouldEqual, 4)
+				So(breaker.State("a.com"), ShouldEqual, http.StateOpen)
+			})
+			Convey("should keep other hosts closed", func() {
+				status = native.StatusOK
+
+				So(call("b.com"), ShouldBeNil)
+				So(breaker.States(), ShouldResemble, map[string]http.BreakerState{
+					"a.com": http.StateOpen,
+					"b.com": http.StateClosed,
+				})
+			})
+			Convey("after the cooldown", func() {
+				now = now.Add(10 * time.Second)
+
+				So(breaker.State("a.com"), ShouldEqual, http.StateHalfOpen)
+
+				Convey("should close once the trial requests succeed", func() {
+					status = native.StatusOK
+
+					So(call("a.com"), ShouldBeNil)
+					So(breaker.State("a.com"), ShouldEqual, http.StateHalfOpen)
+					So(call("a.com"), ShouldBeNil)
+					So(breaker.State("a.com"), ShouldEqual, http.StateClosed)
+				})
+				Convey("should hand the slot of a canceled trial request back", func() {
+					sendErr = context.Canceled
+
+					for i := 0; i < 3; i++ {
+						So(errors.Is(call("a.com"), context.Canceled), ShouldBeTrue)
+					}
+
+					So(breaker.State("a.com"), ShouldEqual, http.StateHalfOpen)
+
+					sendErr = nil
+					status = native.StatusOK
+
+					So(call("a.com"), ShouldBeNil)
+					So(breaker.State("a.com"), ShouldEqual, http.StateHalfOpen)
+					So(call("a.com"), ShouldBeNil)
+					So(breaker.State("a.com"), ShouldEqual, http.StateClosed)
+				})
+				Convey("should open again when a trial request fails", func() {
+					So(call("a.com"), ShouldBeError, "502: : bad requestBroker")
+					So(breaker.State("a.com"), ShouldEqual, http.StateOpen)
+					So(errors.Is(call("a.com"), http.ErrCircuitOpen), ShouldBeTrue)
+				})
+			})
+		})
+		Convey("should count transport errors but not canceled requests", func() {
+			failing := &http.NativeMock{}
+			failing.On("Do", mock.Anything).Return(nil, context.Canceled).Times(4)
+			failing.On("Do", mock.Anything).Return(nil, errors.New("connection refused"))
+
+			c := http.NewClient(failing, encoder.NewFactory(), http.WithCircuitBreaker(breaker))
+
+			for i := 0; i < 8; i++ {
+				_ = http.NewRequest(c).URL("https://c.com").DoAndUnmarshal(ctx, &struct{}{})
+			}
+
+			So(breaker.State("c.com"), ShouldEqual, http.StateOpen)
+		})
+		Convey("state should print its name", func() {
+			So(http.StateClosed.String(), ShouldEqual, "closed")
+			So(http.StateOpen.String(), ShouldEqual, "open")
+			So(http.StateHalfOpen.String(), ShouldEqual, "half-open")
+		})
+	})
+}
@@ -0,0 +1,536 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	native "net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kevinanthony/gorps/v2/header"
+
+	"github.com/pkg/errors"
+)
+
+// defaultMaxCacheBody keeps a single large response from filling the store.
+const defaultMaxCacheBody = 1 << 20
+
+// heuristicFraction of the time since Last-Modified is how long a response
+// without explicit freshness stays fresh (RFC 9111 section 4.2.2).
+const heuristicFraction = 10
+
+// cacheableStatus holds the statuses that may be stored (RFC 9110 section
+// 15.1).
+var cacheableStatus = map[int]bool{
+	native.StatusOK:                   true,
+	native.StatusNonAuthoritativeInfo: true,
+	native.StatusNoContent:            true,
+	native.StatusMultipleChoices:      true,
+	native.StatusMovedPermanently:     true,
+	native.StatusPermanentRedirect:    true,
+	native.StatusNotFound:             true,
+	native.StatusMethodNotAllowed:     true,
+	native.StatusGone:                 true,
+	native.StatusRequestURITooLong:    true,
+	native.StatusNotImplemented:       true,
+}
+
+// CacheEntry is a response kept in a CacheStore. Its fields are exported so
+// stores can serialize it.
+type CacheEntry struct {
+	StatusCode int
+	Header     native.Header
+	// Body is the decompressed response body.
+	Body []byte
+	// VaryHeader holds the request headers named by the Vary response header,
+	// the entry is only used for requests that send the same values. It always
+	// holds a hash of the Authorization the response was fetched with, if any.
+	VaryHeader native.Header
+	// RequestTime and ResponseTime are when the request that stored or last
+	// revalidated the entry was sent and answered.
+	RequestTime  time.Time
+	ResponseTime time.Time
+}
+
+// CacheStore keeps the responses of a Cache. Implementations must be safe for
+// concurrent use and must not modify the entries they are given.
+//
+//go:generate mockery --name=CacheStore --structname=CacheStoreMock --filename=cache_store_mock.go --inpackage
+type CacheStore interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	Delete(key string)
+}
+
+// CacheOption configures a Cache.
+type CacheOption func(c *Cache)
+
+// Cache is a private HTTP cache (RFC 9111) for the GET requests of a Client.
+// Fresh responses are served without a request, stale ones are revalidated
+// with If-None-Match or If-Modified-Since, and successful unsafe requests
+// invalidate what is stored for their url.
+type Cache struct {
+	store   CacheStore
+	maxBody int64
+	now     func() time.Time
+}
+
+func NewCache(store CacheStore, opts ...CacheOption) *Cache {
+	if store == nil {
+		panic("cache store is required")
+	}
+
+	c := &Cache{
+		store:   store,
+		maxBody: defaultMaxCacheBody,
+		now:     time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// WithMaxCacheBody sets the size of the largest response body that is stored,
+// 1MiB by default.
+func WithMaxCacheBody(n int64) CacheOption {
+	return func(c *Cache) {
+		c.maxBody = n
+	}
+}
+
+// WithCacheClock replaces time.Now, so tests can move time forward.
+func WithCacheClock(now func() time.Time) CacheOption {
+	return func(c *Cache) {
+		c.now = now
+	}
+}
+
+// do answers req from the store when it can and sends it otherwise, storing
+// the response if it may be reused.
+func (c *Cache) do(req *native.Request, send func(*native.Request) (*native.Response, error)) (*native.Response, error) {
+	if req.Method != native.MethodGet {
+		resp, err := send(req)
+		if err == nil && !isSafe(req.Method) && resp.StatusCode < native.StatusBadRequest {
+			c.invalidate(req, resp)
+		}
+
+		return resp, err
+	}
+
+	directives := parseCacheControl(req.Header)
+	if directives.has("no-store") || isConditional(req) || ownCredentials(req) {
+		return send(req)
+	}
+
+	key := cacheKey(req.URL)
+
+	entry, ok := c.store.Get(key)
+	if ok && !entry.matches(req) {
+		ok = false
+	}
+
+	if ok && c.usable(entry, directives) {
+		closeBody(req)
+
+		return entry.response(req, c.age(entry)), nil
+	}
+
+	sent := req
+	if ok {
+		sent = revalidation(req, entry)
+	}
+
+	requestTime := c.now()
+
+	resp, err := send(sent)
+	if err != nil {
+		return nil, err
+	}
+
+	responseTime := c.now()
+
+	if ok && resp.StatusCode == native.StatusNotModified {
+		if resp.Body != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrain))
+			_ = resp.Body.Close()
+		}
+
+		entry = entry.revalidated(resp.Header, requestTime, responseTime)
+		c.store.Set(key, entry)
+
+		return entry.response(req, c.age(entry)), nil
+	}
+
+	if c.storable(resp) {
+		c.capture(key, req, resp, requestTime, responseTime)
+	}
+
+	return resp, nil
+}
+
+// usable reports whether entry may be served without asking the server, given
+// the Cache-Control directives of the request.
+func (c *Cache) usable(entry *CacheEntry, directives cacheControl) bool {
+	stored := parseCacheControl(entry.Header)
+	if directives.has("no-cache") || stored.has("no-cache") {
+		return false
+	}
+
+	age := c.age(entry)
+	lifetime := entry.lifetime()
+
+	if maxAge, ok := directives.seconds("max-age"); ok && age > maxAge {
+		return false
+	}
+
+	if minFresh, ok := directives.seconds("min-fresh"); ok {
+		age += minFresh
+	}
+
+	if age < lifetime {
+		return true
+	}
+
+	if !directives.has("max-stale") || stored.has("must-revalidate") {
+		return false
+	}
+
+	maxStale, ok := directives.seconds("max-stale")
+
+	return !ok || age-lifetime <= maxStale
+}
+
+// age is the current age of entry (RFC 9111 section 4.2.3).
+func (c *Cache) age(entry *CacheEntry) time.Duration {
+	apparent := max(0, entry.ResponseTime.Sub(entry.date()))
+
+	var ageValue time.Duration
+	if seconds, err := strconv.ParseInt(entry.Header.Get(header.Age), 10, 64); err == nil && seconds > 0 {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+
+	corrected := ageValue + entry.ResponseTime.Sub(entry.RequestTime)
+
+	return max(apparent, corrected) + c.now().Sub(entry.ResponseTime)
+}
+
+// storable reports whether resp may be stored and could be reused, either
+// because it states how long it is fresh or because it can be revalidated.
+func (c *Cache) storable(resp *native.Response) bool {
+	if !cacheableStatus[resp.StatusCode] || resp.ContentLength > c.maxBody {
+		return false
+	}
+
+	directives := parseCacheControl(resp.Header)
+	if directives.has("no-store") || varyNames(resp.Header)["*"] {
+		return false
+	}
+
+	return directives.has("max-age") ||
+		len(resp.Header.Get(header.Expires)) > 0 ||
+		len(resp.Header.Get(header.ETag)) > 0 ||
+		len(resp.Header.Get(header.LastModified)) > 0
+}
+
+// capture stores resp once its body has been read to the end.
+func (c *Cache) capture(key string, req *native.Request, resp *native.Response, requestTime, responseTime time.Time) {
+	entry := &CacheEntry{
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		VaryHeader:   native.Header{},
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+	}
+
+	for name := range varyNames(resp.Header) {
+		if values := req.Header.Values(name); len(values) > 0 {
+			entry.VaryHeader[name] = append([]string(nil), values...)
+		}
+	}
+
+	if credentials := credentialHash(req); len(credentials) > 0 {
+		entry.VaryHeader.Set(header.Authorization, credentials)
+	}
+
+	if resp.Body == nil {
+		c.store.Set(key, entry)
+
+		return
+	}
+
+	resp.Body = &cachingBody{
+		ReadCloser: resp.Body,
+		limit:      c.maxBody,
+		store: func(body []byte) {
+			entry.Body = body
+			c.store.Set(key, entry)
+		},
+	}
+}
+
+// invalidate drops what is stored for the url of an unsafe request and the
+// urls its response points at on the same host (RFC 9111 section 4.4).
+func (c *Cache) invalidate(req *native.Request, resp *native.Response) {
+	c.store.Delete(cacheKey(req.URL))
+
+	for _, name := range []string{header.Location, header.ContentLocation} {
+		location := resp.Header.Get(name)
+		if len(location) == 0 {
+			continue
+		}
+
+		target, err := req.URL.Parse(location)
+		if err == nil && target.Host == req.URL.Host {
+			c.store.Delete(cacheKey(target))
+		}
+	}
+}
+
+// matches reports whether req sends the same credentials and the same values
+// for the headers the stored response varies on.
+func (e *CacheEntry) matches(req *native.Request) bool {
+	if credentialHash(req) != e.VaryHeader.Get(header.Authorization) {
+		return false
+	}
+
+	for name := range varyNames(e.Header) {
+		if name == header.Authorization {
+			continue
+		}
+
+		if strings.Join(req.Header.Values(name), ",") != strings.Join(e.VaryHeader.Values(name), ",") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// lifetime is how long the entry is fresh after it was generated (RFC 9111
+// section 4.2.1).
+func (e *CacheEntry) lifetime() time.Duration {
+	if maxAge, ok := parseCacheControl(e.Header).seconds("max-age"); ok {
+		return maxAge
+	}
+
+	date := e.date()
+
+	if expires := e.Header.Get(header.Expires); len(expires) > 0 {
+		at, err := native.ParseTime(expires)
+		if err != nil {
+			// an invalid Expires means already expired
+			return 0
+		}
+
+		return at.Sub(date)
+	}
+
+	if modified, err := native.ParseTime(e.Header.Get(header.LastModified)); err == nil && modified.Before(date) {
+		return date.Sub(modified) / heuristicFraction
+	}
+
+	return 0
+}
+
+// date is when the response was generated according to its Date header, or
+// when it was received if it has none.
+func (e *CacheEntry) date() time.Time {
+	if date, err := native.ParseTime(e.Header.Get(header.Date)); err == nil {
+		return date
+	}
+
+	return e.ResponseTime
+}
+
+// revalidated returns a copy of the entry updated with the headers of a 304
+// response. The stored body is decompressed, so its length and encoding stay.
+func (e *CacheEntry) revalidated(updates native.Header, requestTime, responseTime time.Time) *CacheEntry {
+	updated := *e
+	updated.Header = e.Header.Clone()
+	updated.RequestTime = requestTime
+	updated.ResponseTime = responseTime
+
+	for name, values := range updates {
+		if name == header.ContentLength || name == header.ContentEncoding {
+			continue
+		}
+
+		updated.Header[name] = values
+	}
+
+	return &updated
+}
+
+// response builds a response for req from the entry.
+func (e *CacheEntry) response(req *native.Request, age time.Duration) *native.Response {
+	h := e.Header.Clone()
+	if h == nil {
+		h = native.Header{}
+	}
+
+	h.Set(header.Age, strconv.FormatInt(int64(age/time.Second), 10))
+
+	return &native.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, native.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// revalidation returns a copy of req asking the server whether entry is still
+// current, or req itself when the entry has no validators.
+func revalidation(req *native.Request, entry *CacheEntry) *native.Request {
+	etag := entry.Header.Get(header.ETag)
+	modified := entry.Header.Get(header.LastModified)
+
+	if len(etag) == 0 && len(modified) == 0 {
+		return req
+	}
+
+	conditional := req.Clone(req.Context())
+
+	if len(etag) > 0 {
+		conditional.Header.Set(header.IfNoneMatch, etag)
+	}
+
+	if len(modified) > 0 {
+		conditional.Header.Set(header.IfModifiedSince, modified)
+	}
+
+	return conditional
+}
+
+// credentialHash identifies the Authorization of req without keeping the
+// credentials in the store.
+func credentialHash(req *native.Request) string {
+	value := req.Header.Get(header.Authorization)
+	if len(value) == 0 {
+		return ""
+	}
+
+	return sha256Hex([]byte(value))
+}
+
+// isConditional reports whether the caller made req conditional or partial
+// itself, the server's answer is then passed through untouched.
+func isConditional(req *native.Request) bool {
+	for _, name := range []string{
+		header.IfMatch, header.IfModifiedSince, header.IfNoneMatch,
+		header.IfRange, header.IfUnmodifiedSince, header.Range,
+	} {
+		if len(req.Header.Get(name)) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSafe(method string) bool {
+	switch method {
+	case native.MethodGet, native.MethodHead, native.MethodOptions, native.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+func cacheKey(u *url.URL) string {
+	keyURL := *u
+	keyURL.Fragment = ""
+	keyURL.RawFragment = ""
+
+	return keyURL.String()
+}
+
+// varyNames returns the canonical names of the headers in Vary.
+func varyNames(h native.Header) map[string]bool {
+	names := map[string]bool{}
+
+	for _, value := range h.Values(header.Vary) {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); len(name) > 0 {
+				names[native.CanonicalHeaderKey(name)] = true
+			}
+		}
+	}
+
+	return names
+}
+
+// cacheControl holds the directives of Cache-Control headers by lower case
+// name.
+type cacheControl map[string]string
+
+func parseCacheControl(h native.Header) cacheControl {
+	directives := cacheControl{}
+
+	for _, value := range h.Values(header.CacheControl) {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if len(name) > 0 {
+				directives[strings.ToLower(name)] = strings.Trim(arg, "\"")
+			}
+		}
+	}
+
+	return directives
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+
+	return ok
+}
+
+// seconds returns the delta-seconds argument of a directive.
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	seconds, err := strconv.ParseInt(cc[name], 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
+
+// cachingBody stores the body once it has been read to the end, bodies that
+// turn out larger than the limit or are closed early are not stored.
+type cachingBody struct {
+	io.ReadCloser
+	buf   bytes.Buffer
+	limit int64
+	store func(body []byte)
+	done  bool
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.done {
+		return n, err
+	}
+
+	b.buf.Write(p[:n])
+
+	switch {
+	case int64(b.buf.Len()) > b.limit:
+		b.done = true
+		b.buf = bytes.Buffer{}
+	case errors.Is(err, io.EOF):
+		b.done = true
+		b.store(b.buf.Bytes())
+	}
+
+	return n, err
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"container/list"
+	"sync"
+)
+
+// LRUCache is an in-memory CacheStore holding up to a fixed number of
+// entries, dropping the least recently used one to make room.
+type LRUCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+func NewLRUCache(capacity int) *LRUCache {
+	if capacity <= 0 {
+		panic("capacity must be positive")
+	}
+
+	return &LRUCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+func (l *LRUCache) Get(key string) (*CacheEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	l.order.MoveToFront(elem)
+
+	return elem.Value.(*lruItem).entry, true
+}
+
+func (l *LRUCache) Set(key string, entry *CacheEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.entries[key]; ok {
+		elem.Value.(*lruItem).entry = entry
+		l.order.MoveToFront(elem)
+
+		return
+	}
+
+	l.entries[key] = l.order.PushFront(&lruItem{key: key, entry: entry})
+
+	if l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruItem).key)
+	}
+}
+
+func (l *LRUCache) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.entries[key]; ok {
+		l.order.Remove(elem)
+		delete(l.entries, key)
+	}
+}
+
+// Len returns the number of stored entries.
+func (l *LRUCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.order.Len()
+}
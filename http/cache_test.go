@@ -0,0 +1,325 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	native "net/http"
+	"testing"
+	"time"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/http"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewCache(t *testing.T) {
+	t.Parallel()
+
+	Convey("NewCache", t, func() {
+		Convey("should panic when the store is nil", func() {
+			So(func() { http.NewCache(nil) }, ShouldPanicWith, "cache store is required")
+		})
+		Convey("should panic when the lru capacity is not positive", func() {
+			So(func() { http.NewLRUCache(0) }, ShouldPanicWith, "capacity must be positive")
+		})
+	})
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	Convey("Cache", t, func() {
+		ctx := context.Background()
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		version := 1
+		headers := native.Header{}
+
+		var sent []*native.Request
+
+		nativeMock := &http.NativeMock{}
+		nativeMock.On("Do", mock.Anything).Return(func(req *native.Request) (*native.Response, error) {
+			sent = append(sent, req)
+
+			if etag := headers.Get("ETag"); len(etag) > 0 && req.Header.Get("If-None-Match") == etag {
+				resp := newResponse(native.StatusNotModified, nil)
+				resp.Header = native.Header{"Date": {now.Format(native.TimeFormat)}}
+
+				return resp, nil
+			}
+
+			resp := newResponse(native.StatusOK, map[string]int{"version": version})
+			resp.Header = headers.Clone()
+			resp.Header.Set("Date", now.Format(native.TimeFormat))
+
+			return resp, nil
+		})
+
+		store := http.NewLRUCache(10)
+		cache := http.NewCache(store, http.WithCacheClock(func() time.Time { return now }))
+		c := http.NewClient(nativeMock, encoder.NewFactory(), http.WithCache(cache))
+
+		get := func(opts ...func(http.RequestBroker) http.RequestBroker) int {
+			broker := http.NewRequest(c).Get().URL("https://a.com/rates")
+			for _, opt := range opts {
+				broker = opt(broker)
+			}
+
+			var dst map[string]int
+
+			So(broker.DoAndUnmarshal(ctx, &dst), ShouldBeNil)
+
+			return dst["version"]
+		}
+
+		Convey("when the response is fresh", func() {
+			headers.Set("Cache-Control", "max-age=60")
+
+			So(get(), ShouldEqual, 1)
+
+			version = 2
+			now = now.Add(30 * time.Second)
+
+			Convey("should serve it without a request", func() {
+				var dst map[string]int
+
+				resp, err := http.NewRequest(c).URL("https://a.com/rates").DoResponse(ctx, &dst)
+
+				So(err, ShouldBeNil)
+				So(dst["version"], ShouldEqual, 1)
+				So(resp.StatusCode, ShouldEqual, native.StatusOK)
+				So(resp.Header.Get("Age"), ShouldEqual, "30")
+				So(resp.URL.String(), ShouldEqual, "https://a.com/rates")
+				So(sent, ShouldHaveLength, 1)
+			})
+			Convey("should serve it to Do", func() {
+				body, err := http.NewRequest(c).URL("https://a.com/rates").Do(ctx)
+				So(err, ShouldBeNil)
+
+				defer body.Close()
+
+				bts, err := io.ReadAll(body)
+
+				So(err, ShouldBeNil)
+				So(string(bts), ShouldEqual, `{"version":1}`)
+				So(sent, ShouldHaveLength, 1)
+			})
+			Convey("should fetch it again once it expired", func() {
+				now = now.Add(30 * time.Second)
+
+				So(get(), ShouldEqual, 2)
+				So(sent, ShouldHaveLength, 2)
+			})
+			Convey("should fetch it again when the request asks for no-cache", func() {
+				So(get(func(b http.RequestBroker) http.RequestBroker {
+					return b.Header("Cache-Control", "no-cache")
+				}), ShouldEqual, 2)
+				So(sent, ShouldHaveLength, 2)
+			})
+			Convey("should fetch it again when it is older than the request max-age", func() {
+				So(get(func(b http.RequestBroker) http.RequestBroker {
+					return b.Header("Cache-Control", "max-age=10")
+				}), ShouldEqual, 2)
+			})
+			Convey("should serve it stale when the request allows max-stale", func() {
+				now = now.Add(time.Minute)
+
+				So(get(func(b http.RequestBroker) http.RequestBroker {
+					return b.Header("Cache-Control", "max-stale=60")
+				}), ShouldEqual, 1)
+				So(sent, ShouldHaveLength, 1)
+			})
+			Convey("should not store for other urls", func() {
+				var dst map[string]int
+
+				So(http.NewRequest(c).URL("https://a.com/other").DoAndUnmarshal(ctx, &dst), ShouldBeNil)
+				So(sent, ShouldHaveLength, 2)
+			})
+			Convey("should not serve it to a request with its own authenticator", func() {
+				So(get(func(b http.RequestBroker) http.RequestBroker {
+					return b.Auth(http.BearerToken("other"))
+				}), ShouldEqual, 2)
+				So(sent, ShouldHaveLength, 2)
+			})
+			Convey("should not serve it to a request with other credentials", func() {
+				So(get(func(b http.RequestBroker) http.RequestBroker {
+					return b.Header("Authorization", "Bearer other")
+				}), ShouldEqual, 2)
+				So(sent, ShouldHaveLength, 2)
+
+				entry, ok := store.Get("https://a.com/rates")
+
+				So(ok, ShouldBeTrue)
+				So(entry.VaryHeader.Get("Authorization"), ShouldNotBeEmpty)
+				So(entry.VaryHeader.Get("Authorization"), ShouldNotContainSubstring, "other")
+			})
+			Convey("should be invalidated by an unsafe request", func() {
+				So(http.NewRequest(c).Post().URL("https://a.com/rates").DoAndUnmarshal(ctx, &struct{}{}), ShouldBeNil)
+				So(get(), ShouldEqual, 2)
+				So(sent, ShouldHaveLength, 3)
+			})
+		})
+		Convey("should serve the Client's authenticated requests", func() {
+			c = http.NewClient(nativeMock, encoder.NewFactory(), http.WithCache(cache),
+				http.WithAuth(http.BearerToken("token")))
+			headers.Set("Cache-Control", "max-age=60")
+
+			So(get(), ShouldEqual, 1)
+
+			version = 2
+
+			So(get(), ShouldEqual, 1)
+			So(get(), ShouldEqual, 1)
+			So(sent, ShouldHaveLength, 1)
+			So(sent[0].Header.Get("Authorization"), ShouldEqual, "Bearer token")
+		})
+		Convey("should be invalidated by an unsafe request with an api key in the query", func() {
+			c = http.NewClient(nativeMock, encoder.NewFactory(), http.WithCache(cache),
+				http.WithAuth(http.APIKeyQuery("key", "secret")))
+			headers.Set("Cache-Control", "max-age=60")
+
+			So(get(), ShouldEqual, 1)
+			So(http.NewRequest(c).Post().URL("https://a.com/rates").DoAndUnmarshal(ctx, &struct{}{}), ShouldBeNil)
+
+			version = 2
+
+			So(get(), ShouldEqual, 2)
+			So(sent, ShouldHaveLength, 3)
+			So(sent[0].URL.Query().Get("key"), ShouldEqual, "secret")
+		})
+		Convey("should honor Expires", func() {
+			headers.Set("Expires", now.Add(time.Minute).Format(native.TimeFormat))
+
+			So(get(), ShouldEqual, 1)
+
+			version = 2
+			now = now.Add(59 * time.Second)
+
+			So(get(), ShouldEqual, 1)
+
+			now = now.Add(time.Second)
+
+			So(get(), ShouldEqual, 2)
+		})
+		Convey("when the response has an ETag", func() {
+			headers.Set("ETag", `"v1"`)
+			headers.Set("Cache-Control", "no-cache")
+
+			So(get(), ShouldEqual, 1)
+
+			Convey("should revalidate and serve it when unchanged", func() {
+				version = 2
+
+				So(get(), ShouldEqual, 1)
+				So(sent, ShouldHaveLength, 2)
+				So(sent[1].Header.Get("If-None-Match"), ShouldEqual, `"v1"`)
+			})
+			Convey("should use the new response when changed", func() {
+				version = 2
+				headers.Set("ETag", `"v2"`)
+
+				So(get(), ShouldEqual, 2)
+				So(get(), ShouldEqual, 2)
+				So(sent[2].Header.Get("If-None-Match"), ShouldEqual, `"v2"`)
+			})
+			Convey("should pass the caller's conditional request through", func() {
+				resp, err := http.NewRequest(c).URL("https://a.com/rates").Header("If-None-Match", `"v1"`).
+					DoResponse(ctx, &struct{}{})
+
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, native.StatusNotModified)
+			})
+		})
+		Convey("should revalidate with Last-Modified", func() {
+			headers.Set("Last-Modified", now.Add(-time.Hour).Format(native.TimeFormat))
+
+			So(get(), ShouldEqual, 1)
+
+			version = 2
+			now = now.Add(5 * time.Minute)
+
+			So(get(), ShouldEqual, 1)
+			So(sent, ShouldHaveLength, 1)
+
+			now = now.Add(5 * time.Minute)
+
+			So(get(), ShouldEqual, 2)
+			So(sent[1].Header.Get("If-Modified-Since"), ShouldEqual, headers.Get("Last-Modified"))
+		})
+		Convey("should not store no-store responses", func() {
+			headers.Set("Cache-Control", "no-store, max-age=60")
+
+			So(get(), ShouldEqual, 1)
+			So(get(), ShouldEqual, 1)
+			So(sent, ShouldHaveLength, 2)
+			So(store.Len(), ShouldEqual, 0)
+		})
+		Convey("should not store bodies over the limit", func() {
+			cache := http.NewCache(store, http.WithMaxCacheBody(4))
+			c = http.NewClient(nativeMock, encoder.NewFactory(), http.WithCache(cache))
+			headers.Set("Cache-Control", "max-age=60")
+
+			So(get(), ShouldEqual, 1)
+			So(store.Len(), ShouldEqual, 0)
+		})
+		Convey("should keep the response apart per Vary header", func() {
+			headers.Set("Cache-Control", "max-age=60")
+			headers.Set("Vary", "Accept-Language")
+
+			english := func(b http.RequestBroker) http.RequestBroker { return b.Header("Accept-Language", "en") }
+			german := func(b http.RequestBroker) http.RequestBroker { return b.Header("Accept-Language", "de") }
+
+			So(get(english), ShouldEqual, 1)
+			So(get(english), ShouldEqual, 1)
+
+			version = 2
+
+			So(get(german), ShouldEqual, 2)
+			So(sent, ShouldHaveLength, 2)
+		})
+	})
+}
+
+func TestLRUCache(t *testing.T) {
+	t.Parallel()
+
+	Convey("LRUCache", t, func() {
+		store := http.NewLRUCache(2)
+		first, second, third := &http.CacheEntry{}, &http.CacheEntry{}, &http.CacheEntry{}
+
+		store.Set("first", first)
+		store.Set("second", second)
+
+		Convey("should return stored entries", func() {
+			entry, ok := store.Get("first")
+
+			So(ok, ShouldBeTrue)
+			So(entry, ShouldEqual, first)
+		})
+		Convey("should drop the least recently used entry", func() {
+			store.Get("first")
+			store.Set("third", third)
+
+			_, ok := store.Get("second")
+
+			So(ok, ShouldBeFalse)
+			So(store.Len(), ShouldEqual, 2)
+		})
+		Convey("should replace an entry", func() {
+			store.Set("first", third)
+
+			entry, _ := store.Get("first")
+
+			So(entry, ShouldEqual, third)
+			So(store.Len(), ShouldEqual, 2)
+		})
+		Convey("should delete an entry", func() {
+			store.Delete("first")
+
+			_, ok := store.Get("first")
+
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
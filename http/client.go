@@ -1,23 +1,34 @@
 package http
 
 import (
+	"bufio"
+	"context"
+	"fmt"
 	"io"
 	native "net/http"
+	"net/url"
 	"strings"
+	"time"
 
+	"github.com/kevinanthony/gorps/v2/compress"
 	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/header"
 
 	"github.com/pkg/errors"
 )
 
-var errBadRequest = errors.New("bad requestBroker")
+var (
+	errBadRequest = errors.New("bad requestBroker")
+	errHeadBody   = errors.New("HEAD responses have no body, use Do instead of DoAndUnmarshal")
+)
 
 //go:generate mockery --srcpkg=io --name=ReadCloser --structname=BodyMock --filename=body_mock.go --output . --outpkg=http
 
 //go:generate mockery --name=Client --structname=ClientMock --filename=client_mock.go --inpackage
 type Client interface {
 	DoAndUnmarshal(req *native.Request, v interface{}) error
-	Do(req *native.Request) (io.Reader, error)
+	Do(req *native.Request) (io.ReadCloser, error)
+	DoResponse(req *native.Request, v interface{}) (*Response, error)
 }
 
 //go:generate mockery --name=Native --structname=NativeMock --filename=native_mock.go --inpackage
@@ -25,16 +36,29 @@ type Native interface {
 	Do(req *native.Request) (*native.Response, error)
 }
 
+// ClientOption configures a Client.
+type ClientOption func(c *client)
+
 type client struct {
-	encFactory encoder.Factory
-	client     Native
+	encFactory     encoder.Factory
+	client         Native
+	acceptEncoding string
+	baseURL        *url.URL
+	timeout        time.Duration
+	auth           Authenticator
+	signer         Signer
+	breaker        *CircuitBreaker
+	limiters       []*RateLimiter
+	cache          *Cache
+	coalescer      *coalescer
+	hedger         *Hedger
 }
 
 func NewNativeClient() Native {
 	return &native.Client{}
 }
 
-func NewClient(nativeClient Native, enc encoder.Factory) Client {
+func NewClient(nativeClient Native, enc encoder.Factory, opts ...ClientOption) Client {
 	if nativeClient == nil {
 		panic("http client is required")
 	}
@@ -43,48 +67,182 @@ func NewClient(nativeClient Native, enc encoder.Factory) Client {
 		panic("encoding factory is required")
 	}
 
-	return &client{
-		encFactory: enc,
-		client:     nativeClient,
+	c := &client{
+		encFactory:     enc,
+		client:         nativeClient,
+		acceptEncoding: strings.Join(compress.Supported(), ", "),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// WithAcceptEncoding sets the encodings advertised in Accept-Encoding when a
+// request does not set the header itself. Passing none stops advertising, any
+// compressed response is still decoded.
+func WithAcceptEncoding(encodings ...string) ClientOption {
+	return func(c *client) {
+		c.acceptEncoding = strings.Join(encodings, ", ")
+	}
+}
+
+// WithBaseURL resolves requests without a host against base, the request path
+// is appended to the base path and query parameters are merged.
+func WithBaseURL(base string) ClientOption {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		panic(fmt.Sprintf("invalid base url: %s", err))
+	}
+
+	if len(baseURL.Host) == 0 {
+		panic("base url must be absolute")
+	}
+
+	return func(c *client) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithTimeout sets the default time a request may take, from connecting until
+// the response body is read. A RequestBroker Timeout overrides it per request.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *client) {
+		c.timeout = d
+	}
+}
+
+// WithAuth authenticates every request with auth, unless the RequestBroker
+// sets its own with Auth.
+func WithAuth(auth Authenticator) ClientOption {
+	return func(c *client) {
+		c.auth = auth
+	}
+}
+
+// WithSigner signs every request with signer, unless the RequestBroker sets its
+// own with Sign.
+func WithSigner(signer Signer) ClientOption {
+	return func(c *client) {
+		c.signer = signer
+	}
+}
+
+// WithCircuitBreaker stops sending requests to hosts that keep failing, they
+// fail with a *CircuitOpenError instead until breaker lets them through.
+func WithCircuitBreaker(breaker *CircuitBreaker) ClientOption {
+	return func(c *client) {
+		c.breaker = breaker
+	}
+}
+
+// WithRateLimiter holds requests back to stay within limiter, it can be given
+// more than once, e.g. for a limit for the whole Client and one per host.
+func WithRateLimiter(limiter *RateLimiter) ClientOption {
+	return func(c *client) {
+		c.limiters = append(c.limiters, limiter)
+	}
+}
+
+// WithCache serves GET requests from cache when the server allows it and
+// revalidates stale responses instead of fetching them again.
+func WithCache(cache *Cache) ClientOption {
+	return func(c *client) {
+		c.cache = cache
+	}
+}
+
+// WithHedging hedges every idempotent request with hedger, unless the
+// RequestBroker sets its own with Hedge.
+func WithHedging(hedger *Hedger) ClientOption {
+	return func(c *client) {
+		c.hedger = hedger
 	}
 }
 
 func (c client) DoAndUnmarshal(req *native.Request, dst interface{}) error {
-	resp, err := c.client.Do(req)
+	if req.Method == native.MethodHead {
+		return errHeadBody
+	}
+
+	_, err := c.DoResponse(req, dst)
+
+	return err
+}
+
+// DoResponse decodes the response body into dst like DoAndUnmarshal and
+// returns the response metadata. For error statuses the Response is returned
+// along with the error, so headers like Retry-After can still be read.
+func (c client) DoResponse(req *native.Request, dst interface{}) (*Response, error) {
+	start := time.Now()
+
+	resp, bts, err := c.fetch(req)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if resp.StatusCode >= native.StatusBadRequest {
+		return newResponse(resp, req, nil, start), errors.Wrapf(errBadRequest, "%d: %s",
+			resp.StatusCode, strings.Trim(string(bts), "\""))
+	}
+
+	if len(bts) == 0 || req.Method == native.MethodHead {
+		return newResponse(resp, req, nil, start), nil
+	}
+
+	if err := c.encFactory.CreateFromResponse(resp).Decode(bts, dst); err != nil {
+		return nil, err
+	}
+
+	return newResponse(resp, req, dst, start), nil
+}
+
+// fetch sends req and reads the whole response body, sharing the call with
+// identical requests in flight when coalescing is on.
+func (c client) fetch(req *native.Request) (*native.Response, []byte, error) {
+	if c.coalescer == nil || !coalescable(req) {
+		return c.fetchOnce(req)
+	}
+
+	c.resolve(req)
+
+	return c.coalescer.do(req, c.fetchOnce)
+}
+
+func (c client) fetchOnce(req *native.Request) (*native.Response, []byte, error) {
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	defer func() {
 		if resp.Body != nil {
 			_ = resp.Body.Close()
 		}
+
+		resp.Body = native.NoBody
 	}()
 
 	bts, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	if resp.StatusCode >= native.StatusBadRequest {
-		return errors.Wrapf(errBadRequest, "%d: %s",
-			resp.StatusCode, strings.Trim(string(bts), "\""))
-	}
-
-	if len(bts) == 0 {
-		return nil
-	}
-
-	return c.encFactory.CreateFromResponse(resp).Decode(bts, dst)
+	return resp, bts, nil
 }
 
-func (c client) Do(req *native.Request) (io.Reader, error) {
-	resp, err := c.client.Do(req) //nolint:bodyclose // this gets passed upstream, it's for them to close
+// Do returns the response body, which the caller must close.
+func (c client) Do(req *native.Request) (io.ReadCloser, error) {
+	resp, err := c.do(req) //nolint:bodyclose // this gets passed upstream, it's for them to close
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.StatusCode >= native.StatusBadRequest {
+		defer resp.Body.Close()
+
 		bts, err := io.ReadAll(resp.Body)
 		if err != nil {
 			return nil, err
@@ -94,5 +252,283 @@ func (c client) Do(req *native.Request) (io.Reader, error) {
 			resp.StatusCode, strings.Trim(string(bts), "\""))
 	}
 
+	if req.Method == native.MethodHead {
+		if resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+
+		return native.NoBody, nil
+	}
+
 	return resp.Body, nil
 }
+
+// do advertises the supported encodings and transparently decompresses the
+// response, so it works the same whether or not Native already did it.
+func (c client) do(req *native.Request) (*native.Response, error) {
+	c.resolve(req)
+
+	if len(c.acceptEncoding) > 0 && len(req.Header.Get(header.AcceptEncoding)) == 0 {
+		if req.Header == nil {
+			req.Header = native.Header{}
+		}
+
+		req.Header.Set(header.AcceptEncoding, c.acceptEncoding)
+	}
+
+	if c.cache != nil {
+		return c.cache.do(req, c.hedged)
+	}
+
+	return c.hedged(req)
+}
+
+// hedged sends a second attempt of idempotent requests that are slow to be
+// answered, if there is a hedger.
+func (c client) hedged(req *native.Request) (*native.Response, error) {
+	hedger := hedgerFrom(req.Context(), c.hedger)
+	if hedger == nil || !isIdempotent(req.Method) || !concurrentBody(req) {
+		return c.limited(req)
+	}
+
+	return hedger.do(req, c.limited)
+}
+
+// limited sends req once every rate limiter lets it through.
+func (c client) limited(req *native.Request) (*native.Response, error) {
+	for _, limiter := range c.limiters {
+		if err := limiter.wait(req); err != nil {
+			closeBody(req)
+
+			return nil, err
+		}
+	}
+
+	resp, err := c.guarded(req)
+
+	for _, limiter := range c.limiters {
+		limiter.observe(req, resp)
+	}
+
+	return resp, err
+}
+
+// guarded sends req unless the circuit breaker refuses it.
+func (c client) guarded(req *native.Request) (*native.Response, error) {
+	if c.breaker == nil {
+		return c.timed(req)
+	}
+
+	host := req.URL.Host
+	if err := c.breaker.allow(host); err != nil {
+		closeBody(req)
+
+		return nil, err
+	}
+
+	resp, err := c.timed(req)
+	c.breaker.record(host, resp, err)
+
+	return resp, err
+}
+
+// timed sends req within the request or client timeout, if there is one.
+func (c client) timed(req *native.Request) (*native.Response, error) {
+	timeout := timeoutFrom(req.Context(), c.timeout)
+	if timeout <= 0 {
+		return c.authenticated(req)
+	}
+
+	deadline := time.Now().Add(timeout)
+	ctx, cancel := context.WithDeadline(req.Context(), deadline)
+	tracker, ctx := newPhaseTracker(ctx, timeout, deadline)
+
+	resp, err := c.authenticated(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+
+		return nil, tracker.wrap(err, PhaseResponseHeader)
+	}
+
+	if resp.Body == nil {
+		cancel()
+
+		return resp, nil
+	}
+
+	resp.Body = timeoutBody{
+		ReadCloser: resp.Body,
+		tracker:    tracker,
+		cancel:     cancel,
+	}
+
+	return resp, nil
+}
+
+// authenticated sends req with credentials and signed and, when the
+// credentials were refused with a 401, retries once with fresh ones if the
+// authenticator can refresh them.
+func (c client) authenticated(req *native.Request) (*native.Response, error) {
+	auth := authFrom(req.Context(), c.auth)
+	signer := signerFrom(req.Context(), c.signer)
+
+	if auth != nil || signer != nil {
+		// credentials go on a copy, the cache still looks at the headers and
+		// url of req once the response is in
+		req = req.Clone(req.Context())
+	}
+
+	if err := prepare(req, auth, signer); err != nil {
+		closeBody(req)
+
+		return nil, err
+	}
+
+	resp, err := c.send(req)
+	if auth == nil || err != nil || resp.StatusCode != native.StatusUnauthorized {
+		return resp, err
+	}
+
+	refresher, ok := auth.(Refresher)
+	if !ok {
+		return resp, nil
+	}
+
+	retry, ok := replayable(req)
+	if !ok {
+		return resp, nil
+	}
+
+	if resp.Body != nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	refresher.Invalidate()
+
+	if err := prepare(retry, auth, signer); err != nil {
+		closeBody(retry)
+
+		return nil, err
+	}
+
+	return c.send(retry)
+}
+
+// prepare adds the credentials and then the signature, so the signature
+// covers credentials sent in the query or a signed header. Signers refuse to
+// replace credentials sent in Authorization.
+func prepare(req *native.Request, auth Authenticator, signer Signer) error {
+	if auth != nil {
+		if err := auth.Authenticate(req); err != nil {
+			return errors.Wrap(err, "authenticate")
+		}
+	}
+
+	if signer != nil {
+		if err := signer.Sign(req); err != nil {
+			return errors.Wrap(err, "sign")
+		}
+	}
+
+	return nil
+}
+
+// closeBody closes the body of a request that won't be sent, as Native does
+// for requests it fails to send, so streamed bodies release what they hold.
+func closeBody(req *native.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
+
+func (c client) send(req *native.Request) (*native.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := decompress(resp); err != nil {
+		if resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (c client) resolve(req *native.Request) {
+	if c.baseURL == nil || req.URL == nil || len(req.URL.Host) > 0 {
+		return
+	}
+
+	resolved := c.baseURL.JoinPath(req.URL.EscapedPath())
+
+	query := c.baseURL.Query()
+	for k, values := range req.URL.Query() {
+		query[k] = values
+	}
+
+	resolved.RawQuery = query.Encode()
+	resolved.Fragment = req.URL.Fragment
+
+	req.URL = resolved
+	req.Host = resolved.Host
+}
+
+func decompress(resp *native.Response) error {
+	encoding := resp.Header.Get(header.ContentEncoding)
+	if resp.Body == nil || len(encoding) == 0 || !hasBody(resp) {
+		return nil
+	}
+
+	comp, ok := compress.FromEncoding(encoding)
+	if !ok {
+		return nil
+	}
+
+	// an empty body has nothing to decompress, and not every decompressor
+	// reports that as io.EOF
+	body := bufio.NewReader(resp.Body)
+	if _, err := body.Peek(1); errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	reader, err := comp.NewReader(body)
+	if err != nil {
+		return errors.Wrapf(err, "decompress %s", encoding)
+	}
+
+	resp.Body = decompressedBody{
+		ReadCloser: reader,
+		body:       resp.Body,
+	}
+	resp.Header.Del(header.ContentEncoding)
+	resp.Header.Del(header.ContentLength)
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return nil
+}
+
+// hasBody reports whether resp may carry a body at all (RFC 9110 section 6.4.1).
+func hasBody(resp *native.Response) bool {
+	if resp.StatusCode == native.StatusNoContent || resp.StatusCode == native.StatusNotModified {
+		return false
+	}
+
+	return resp.Request == nil || resp.Request.Method != native.MethodHead
+}
+
+type decompressedBody struct {
+	io.ReadCloser
+	body io.Closer
+}
+
+func (d decompressedBody) Close() error {
+	_ = d.ReadCloser.Close()
+
+	return d.body.Close()
+}
@@ -15,19 +15,19 @@ type ClientMock struct {
 }
 
 // Do provides a mock function with given fields: req
-func (_m *ClientMock) Do(req *nethttp.Request) (io.Reader, error) {
+func (_m *ClientMock) Do(req *nethttp.Request) (io.ReadCloser, error) {
 	ret := _m.Called(req)
 
-	var r0 io.Reader
+	var r0 io.ReadCloser
 	var r1 error
-	if rf, ok := ret.Get(0).(func(*nethttp.Request) (io.Reader, error)); ok {
+	if rf, ok := ret.Get(0).(func(*nethttp.Request) (io.ReadCloser, error)); ok {
 		return rf(req)
 	}
-	if rf, ok := ret.Get(0).(func(*nethttp.Request) io.Reader); ok {
+	if rf, ok := ret.Get(0).(func(*nethttp.Request) io.ReadCloser); ok {
 		r0 = rf(req)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(io.Reader)
+			r0 = ret.Get(0).(io.ReadCloser)
 		}
 	}
 
@@ -54,6 +54,32 @@ func (_m *ClientMock) DoAndUnmarshal(req *nethttp.Request, v interface{}) error
 	return r0
 }
 
+// DoResponse provides a mock function with given fields: req, v
+func (_m *ClientMock) DoResponse(req *nethttp.Request, v interface{}) (*Response, error) {
+	ret := _m.Called(req, v)
+
+	var r0 *Response
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*nethttp.Request, interface{}) (*Response, error)); ok {
+		return rf(req, v)
+	}
+	if rf, ok := ret.Get(0).(func(*nethttp.Request, interface{}) *Response); ok {
+		r0 = rf(req, v)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Response)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*nethttp.Request, interface{}) error); ok {
+		r1 = rf(req, v)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewClientMock creates a new instance of ClientMock. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewClientMock(t interface {
@@ -2,11 +2,16 @@ package http_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	native "net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/kevinanthony/gorps/v2/compress"
 	"github.com/kevinanthony/gorps/v2/encoder"
 	"github.com/kevinanthony/gorps/v2/http"
 
@@ -152,6 +157,470 @@ func TestClient_DoAndUnmarshal(t *testing.T) {
 	})
 }
 
+func TestClient_Head(t *testing.T) {
+	t.Parallel()
+
+	Convey("Head", t, func() {
+		req, err := native.NewRequest(http.MethodHead, "https://test.com/test", nil)
+		So(err, ShouldBeNil)
+
+		clientMock := &http.NativeMock{}
+		bodyMock := &http.BodyMock{}
+		client := http.NewClient(clientMock, encoder.NewFactory())
+
+		Convey("Do should close body and return empty reader", func() {
+			resp := newResponse(native.StatusOK, nil)
+			resp.Body = bodyMock
+			bodyMock.On("Close").Return(nil).Once()
+			clientMock.On("Do", req).Return(resp, nil).Once()
+
+			reader, err := client.Do(req)
+			So(err, ShouldBeNil)
+
+			bts, err := io.ReadAll(reader)
+			So(err, ShouldBeNil)
+			So(bts, ShouldBeEmpty)
+			mock.AssertExpectationsForObjects(t, clientMock, bodyMock)
+		})
+		Convey("Do should return error and close the body when status is >= 400", func() {
+			body := &trackedBody{Reader: strings.NewReader("")}
+			resp := newResponse(native.StatusNotFound, nil)
+			resp.Body = body
+			clientMock.On("Do", req).Return(resp, nil).Once()
+
+			reader, err := client.Do(req)
+
+			So(reader, ShouldBeNil)
+			So(err, ShouldBeError, "404: : bad requestBroker")
+			So(body.closed, ShouldBeTrue)
+		})
+		Convey("DoAndUnmarshal should return error without sending", func() {
+			err := client.DoAndUnmarshal(req, &struct{}{})
+
+			So(err, ShouldBeError, "HEAD responses have no body, use Do instead of DoAndUnmarshal")
+			mock.AssertExpectationsForObjects(t, clientMock)
+		})
+	})
+}
+
+func TestClient_BaseURL(t *testing.T) {
+	t.Parallel()
+
+	Convey("BaseURL", t, func() {
+		clientMock := &http.NativeMock{}
+		client := http.NewClient(clientMock, encoder.NewFactory(), http.WithBaseURL("https://test.com/api/v1?key=abc"))
+
+		Convey("should resolve relative request against base url", func() {
+			req, err := http.NewRequest(client).
+				Path("/users/{id}", map[string]interface{}{"id": "a b"}).
+				Query("page", "2").
+				CreateRequest(context.Background())
+			So(err, ShouldBeNil)
+
+			clientMock.On("Do", req).Return(newResponse(native.StatusOK, nil), nil).Once()
+
+			_, err = client.Do(req)
+
+			So(err, ShouldBeNil)
+			So(req.URL.String(), ShouldEqual, "https://test.com/api/v1/users/a%20b?key=abc&page=2")
+			So(req.Host, ShouldEqual, "test.com")
+		})
+		Convey("should leave absolute request alone", func() {
+			req, err := native.NewRequest(http.MethodGet, "https://other.com/test", nil)
+			So(err, ShouldBeNil)
+
+			clientMock.On("Do", req).Return(newResponse(native.StatusOK, nil), nil).Once()
+
+			_, err = client.Do(req)
+
+			So(err, ShouldBeNil)
+			So(req.URL.String(), ShouldEqual, "https://other.com/test")
+		})
+		Convey("should panic when base url", func() {
+			Convey("is not valid", func() {
+				So(func() { http.WithBaseURL("://") }, ShouldPanicWith, `invalid base url: parse "://": missing protocol scheme`)
+			})
+			Convey("is not absolute", func() {
+				So(func() { http.WithBaseURL("/api") }, ShouldPanicWith, "base url must be absolute")
+			})
+		})
+	})
+}
+
+func TestClient_Compression(t *testing.T) {
+	t.Parallel()
+
+	Convey("Compression", t, func() {
+		req, err := native.NewRequest(http.MethodGet, "https://test.com/test", nil)
+		So(err, ShouldBeNil)
+
+		clientMock := &http.NativeMock{}
+		client := http.NewClient(clientMock, encoder.NewFactory())
+
+		type T struct {
+			Int int `json:"int"`
+		}
+
+		Convey("should advertise supported encodings", func() {
+			clientMock.On("Do", req).Return(newResponse(native.StatusOK, nil), nil).Once()
+
+			err := client.DoAndUnmarshal(req, &T{})
+
+			So(err, ShouldBeNil)
+			So(req.Header.Get("Accept-Encoding"), ShouldEqual, "gzip, deflate, br, zstd")
+			mock.AssertExpectationsForObjects(t, clientMock)
+		})
+		Convey("should keep accept encoding set on request", func() {
+			req.Header.Set("Accept-Encoding", "identity")
+			clientMock.On("Do", req).Return(newResponse(native.StatusOK, nil), nil).Once()
+
+			err := client.DoAndUnmarshal(req, &T{})
+
+			So(err, ShouldBeNil)
+			So(req.Header.Get("Accept-Encoding"), ShouldEqual, "identity")
+		})
+		Convey("should not advertise when disabled", func() {
+			client := http.NewClient(clientMock, encoder.NewFactory(), http.WithAcceptEncoding())
+			clientMock.On("Do", req).Return(newResponse(native.StatusOK, nil), nil).Once()
+
+			err := client.DoAndUnmarshal(req, &T{})
+
+			So(err, ShouldBeNil)
+			So(req.Header.Get("Accept-Encoding"), ShouldBeEmpty)
+		})
+		for _, encoding := range compress.Supported() {
+			encoding := encoding
+
+			Convey("should decode "+encoding+" response", func() {
+				clientMock.On("Do", req).Return(newCompressedResponse(encoding, `{"int":42}`), nil).Once()
+
+				var actual T
+				err := client.DoAndUnmarshal(req, &actual)
+
+				So(err, ShouldBeNil)
+				So(actual, ShouldResemble, T{Int: 42})
+			})
+		}
+		for _, encoding := range compress.Supported() {
+			encoding := encoding
+
+			Convey("should accept an empty "+encoding+" body", func() {
+				for _, tc := range []struct {
+					method string
+					status int
+				}{
+					{native.MethodGet, native.StatusOK},
+					{native.MethodGet, native.StatusNoContent},
+					{native.MethodGet, native.StatusNotModified},
+					{native.MethodHead, native.StatusOK},
+				} {
+					req, err := native.NewRequest(tc.method, "https://test.com/test", nil)
+					So(err, ShouldBeNil)
+
+					resp := newResponse(tc.status, nil)
+					resp.Request = req
+					resp.Header = native.Header{"Content-Encoding": []string{encoding}}
+					clientMock.On("Do", req).Return(resp, nil).Once()
+
+					reader, err := client.Do(req)
+					So(err, ShouldBeNil)
+
+					actual, err := io.ReadAll(reader)
+
+					So(err, ShouldBeNil)
+					So(actual, ShouldBeEmpty)
+				}
+			})
+		}
+		Convey("should decode response returned from Do", func() {
+			resp := newCompressedResponse(compress.Gzip, "plain body")
+			clientMock.On("Do", req).Return(resp, nil).Once()
+
+			reader, err := client.Do(req)
+			So(err, ShouldBeNil)
+
+			actual, err := io.ReadAll(reader)
+
+			So(err, ShouldBeNil)
+			So(string(actual), ShouldEqual, "plain body")
+			So(resp.Header.Get("Content-Encoding"), ShouldBeEmpty)
+		})
+		Convey("should decode error response", func() {
+			resp := newCompressedResponse(compress.Gzip, "you are a teapot")
+			resp.StatusCode = native.StatusTeapot
+			clientMock.On("Do", req).Return(resp, nil).Once()
+
+			_, err := client.Do(req)
+
+			So(err, ShouldBeError, "418: you are a teapot: bad requestBroker")
+		})
+		Convey("should leave unknown encodings alone", func() {
+			resp := newResponse(native.StatusOK, nil)
+			resp.Header = native.Header{"Content-Encoding": []string{"compress"}}
+			resp.Body = io.NopCloser(strings.NewReader("raw"))
+			clientMock.On("Do", req).Return(resp, nil).Once()
+
+			reader, err := client.Do(req)
+			So(err, ShouldBeNil)
+
+			actual, err := io.ReadAll(reader)
+
+			So(err, ShouldBeNil)
+			So(string(actual), ShouldEqual, "raw")
+		})
+		Convey("should return error when body is not compressed as advertised", func() {
+			resp := newResponse(native.StatusOK, nil)
+			resp.Header = native.Header{"Content-Encoding": []string{"gzip"}}
+			resp.Body = io.NopCloser(strings.NewReader("this is definitely not gzip"))
+			clientMock.On("Do", req).Return(resp, nil).Once()
+
+			err := client.DoAndUnmarshal(req, &T{})
+
+			So(err, ShouldBeError, "decompress gzip: gzip: invalid header")
+		})
+	})
+}
+
+func TestClient_Timeout(t *testing.T) {
+	t.Parallel()
+
+	Convey("Timeout", t, func() {
+		ctx := context.Background()
+		timeout := 50 * time.Millisecond
+		server := httptest.NewServer(native.HandlerFunc(func(w native.ResponseWriter, r *native.Request) {
+			if r.URL.Path == "/slow-body" {
+				w.WriteHeader(native.StatusOK)
+				w.(native.Flusher).Flush()
+			}
+
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}))
+
+		Reset(server.Close)
+
+		Convey("should return response header timeout when server is slow to answer", func() {
+			c := http.NewClient(server.Client(), encoder.NewFactory(), http.WithTimeout(timeout))
+
+			_, err := http.NewRequest(c).URL(server.URL + "/slow-header").Do(ctx)
+
+			var timeoutErr *http.TimeoutError
+
+			So(errors.As(err, &timeoutErr), ShouldBeTrue)
+			So(timeoutErr.Phase, ShouldEqual, http.PhaseResponseHeader)
+			So(timeoutErr.Limit, ShouldEqual, timeout)
+			So(err, ShouldBeError, "response header timeout after 50ms")
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+		})
+		Convey("should return body read timeout when body is slow", func() {
+			c := http.NewClient(server.Client(), encoder.NewFactory(), http.WithTimeout(timeout))
+
+			body, err := http.NewRequest(c).URL(server.URL + "/slow-body").Do(ctx)
+			So(err, ShouldBeNil)
+
+			_, err = io.ReadAll(body)
+
+			var timeoutErr *http.TimeoutError
+
+			So(errors.As(err, &timeoutErr), ShouldBeTrue)
+			So(timeoutErr.Phase, ShouldEqual, http.PhaseBody)
+		})
+		Convey("should return connect timeout when no connection is made", func() {
+			nativeMock := &http.NativeMock{}
+			nativeMock.On("Do", mock.Anything).Run(func(args mock.Arguments) {
+				<-args.Get(0).(*native.Request).Context().Done()
+			}).Return(nil, context.DeadlineExceeded).Once()
+
+			c := http.NewClient(nativeMock, encoder.NewFactory(), http.WithTimeout(timeout))
+
+			err := http.NewRequest(c).URL("https://test.com").DoAndUnmarshal(ctx, &map[string]interface{}{})
+
+			var timeoutErr *http.TimeoutError
+
+			So(errors.As(err, &timeoutErr), ShouldBeTrue)
+			So(timeoutErr.Phase, ShouldEqual, http.PhaseConnect)
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+		})
+		Convey("should let the request timeout override the client default", func() {
+			c := http.NewClient(server.Client(), encoder.NewFactory(), http.WithTimeout(time.Hour))
+
+			_, err := http.NewRequest(c).URL(server.URL + "/slow-header").Timeout(timeout).Do(ctx)
+
+			var timeoutErr *http.TimeoutError
+
+			So(errors.As(err, &timeoutErr), ShouldBeTrue)
+			So(timeoutErr.Limit, ShouldEqual, timeout)
+		})
+		Convey("should leave an earlier deadline of the caller alone", func() {
+			c := http.NewClient(server.Client(), encoder.NewFactory(), http.WithTimeout(10*time.Second))
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			_, err := http.NewRequest(c).URL(server.URL + "/slow-header").Do(ctx)
+
+			var timeoutErr *http.TimeoutError
+
+			So(errors.As(err, &timeoutErr), ShouldBeFalse)
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+		})
+		Convey("should not wrap errors that are not timeouts", func() {
+			expected := errors.New("connection refused")
+			nativeMock := &http.NativeMock{}
+			nativeMock.On("Do", mock.Anything).Return(nil, expected).Once()
+
+			c := http.NewClient(nativeMock, encoder.NewFactory(), http.WithTimeout(timeout))
+
+			_, err := http.NewRequest(c).URL("https://test.com").Do(ctx)
+
+			So(err, ShouldEqual, expected)
+		})
+		Convey("should return error when request timeout is not positive", func() {
+			_, err := http.NewRequest(&http.ClientMock{}).URL("https://test.com").Timeout(0).CreateRequest(ctx)
+
+			So(err, ShouldBeError, "timeout must be positive, got 0s")
+		})
+	})
+}
+
+func TestClient_DoResponse(t *testing.T) {
+	t.Parallel()
+
+	Convey("DoResponse", t, func() {
+		ctx := context.Background()
+		server := httptest.NewServer(native.HandlerFunc(func(w native.ResponseWriter, r *native.Request) {
+			switch r.URL.Path {
+			case "/old":
+				native.Redirect(w, r, "/new", native.StatusMovedPermanently)
+			case "/limited":
+				w.Header().Set("Retry-After", "30")
+				w.WriteHeader(native.StatusTooManyRequests)
+				_, _ = w.Write([]byte("slow down"))
+			default:
+				w.Header().Set("Content-Type", encoder.ApplicationJSON)
+				w.Header().Set("ETag", `"v1"`)
+				_, _ = w.Write([]byte(`{"int":42}`))
+			}
+		}))
+
+		Reset(server.Close)
+
+		c := http.NewClient(server.Client(), encoder.NewFactory())
+
+		type T struct {
+			Int int `json:"int"`
+		}
+
+		Convey("should return metadata and decoded body", func() {
+			var actual T
+
+			resp, err := http.NewRequest(c).URL(server.URL+"/old").DoResponse(ctx, &actual)
+
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, native.StatusOK)
+			So(resp.Header.Get("ETag"), ShouldEqual, `"v1"`)
+			So(resp.Body, ShouldEqual, &actual)
+			So(actual.Int, ShouldEqual, 42)
+			So(resp.Duration, ShouldBeGreaterThan, 0)
+			So(resp.URL.String(), ShouldEqual, server.URL+"/new")
+		})
+		Convey("should return response along with error status", func() {
+			resp, err := http.NewRequest(c).URL(server.URL+"/limited").DoResponse(ctx, &T{})
+
+			So(err, ShouldBeError, "429: slow down: bad requestBroker")
+			So(resp.StatusCode, ShouldEqual, native.StatusTooManyRequests)
+			So(resp.Header.Get("Retry-After"), ShouldEqual, "30")
+			So(resp.Body, ShouldBeNil)
+		})
+		Convey("should return headers of HEAD requests", func() {
+			resp, err := http.NewRequest(c).Head().URL(server.URL).DoResponse(ctx, &T{})
+
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, native.StatusOK)
+			So(resp.Header.Get("ETag"), ShouldEqual, `"v1"`)
+			So(resp.Body, ShouldBeNil)
+		})
+	})
+}
+
+func TestClient_UnsentBody(t *testing.T) {
+	t.Parallel()
+
+	Convey("a request that is not sent", t, func() {
+		nativeMock := &http.NativeMock{}
+		nativeMock.On("Do", mock.Anything).Return(func(*native.Request) (*native.Response, error) {
+			return newResponse(native.StatusBadGateway, nil), nil
+		})
+
+		body := &trackedBody{Reader: strings.NewReader("payload")}
+		send := func(c http.Client) error {
+			req, err := native.NewRequest(http.MethodPost, "https://test.com/upload", body)
+			So(err, ShouldBeNil)
+
+			return c.DoAndUnmarshal(req, &struct{}{})
+		}
+
+		Convey("should have its body closed when", func() {
+			Convey("the rate limiter refuses it", func() {
+				limiter := http.NewRateLimiter(1, 1, http.WithFailFast())
+				c := http.NewClient(nativeMock, encoder.NewFactory(), http.WithRateLimiter(limiter))
+
+				_ = send(c)
+				body.closed = false
+
+				So(errors.Is(send(c), http.ErrRateLimited), ShouldBeTrue)
+				So(body.closed, ShouldBeTrue)
+			})
+			Convey("the circuit is open", func() {
+				breaker := http.NewCircuitBreaker(http.WithFailureRatio(0.5, 1))
+				c := http.NewClient(nativeMock, encoder.NewFactory(), http.WithCircuitBreaker(breaker))
+
+				_ = send(c)
+				body.closed = false
+
+				So(errors.Is(send(c), http.ErrCircuitOpen), ShouldBeTrue)
+				So(body.closed, ShouldBeTrue)
+			})
+			Convey("the authenticator fails", func() {
+				auth := http.AuthenticatorFunc(func(*native.Request) error { return errors.New("no token") })
+				c := http.NewClient(nativeMock, encoder.NewFactory(), http.WithAuth(auth))
+
+				So(send(c), ShouldBeError, "authenticate: no token")
+				So(body.closed, ShouldBeTrue)
+			})
+		})
+	})
+}
+func newCompressedResponse(encoding, body string) *native.Response {
+	comp, _ := compress.FromEncoding(encoding)
+
+	var buf bytes.Buffer
+
+	w, err := comp.NewWriter(&buf)
+	if err != nil {
+		panic(err)
+	}
+
+	if _, err := w.Write([]byte(body)); err != nil {
+		panic(err)
+	}
+
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+
+	return &native.Response{
+		Body:       io.NopCloser(&buf),
+		StatusCode: native.StatusOK,
+		Header: native.Header{
+			"Content-Encoding": []string{encoding},
+			"Content-Type":     []string{encoder.ApplicationJSON},
+		},
+	}
+}
+
 func newResponse(status int, data interface{}) *native.Response {
 	var body []byte
 
@@ -0,0 +1,159 @@
+package http
+
+import (
+	"context"
+	native "net/http"
+	"strings"
+	"sync"
+
+	"github.com/kevinanthony/gorps/v2/header"
+)
+
+// WithCoalescing collapses identical GET and HEAD requests made while one is
+// already in flight into a single upstream call, every caller decodes the
+// shared response body into its own value. Requests are identical when their
+// method, url, Authorization and the given headers match. Requests with a body
+// or with their own Auth or Sign are always sent on their own, as is Do, whose
+// body can only be read once.
+func WithCoalescing(headers ...string) ClientOption {
+	names := make([]string, 0, len(headers)+1)
+	names = append(names, header.Authorization)
+
+	for _, name := range headers {
+		names = append(names, native.CanonicalHeaderKey(name))
+	}
+
+	return func(c *client) {
+		c.coalescer = &coalescer{
+			headers: names,
+			flights: map[string]*flight{},
+		}
+	}
+}
+
+type fetchFunc func(req *native.Request) (*native.Response, []byte, error)
+
+// coalescer shares the response of an in-flight request with every identical
+// request made before it finishes.
+type coalescer struct {
+	headers []string
+
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	key     string
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+
+	resp *native.Response
+	body []byte
+	err  error
+}
+
+// coalescable reports whether req may share its response with others.
+func coalescable(req *native.Request) bool {
+	if req.Method != native.MethodGet && req.Method != native.MethodHead {
+		return false
+	}
+
+	if req.Body != nil && req.Body != native.NoBody {
+		return false
+	}
+
+	return !ownCredentials(req)
+}
+
+// do joins the flight for req, starting one if there is none. The upstream
+// call does not depend on any single caller: it is only canceled once every
+// caller waiting for it has given up.
+func (g *coalescer) do(req *native.Request, fetch fetchFunc) (*native.Response, []byte, error) {
+	key := g.key(req)
+
+	g.mu.Lock()
+
+	f, ok := g.flights[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.WithoutCancel(req.Context()))
+		f = &flight{key: key, done: make(chan struct{}), cancel: cancel}
+		g.flights[key] = f
+
+		go g.run(f, req.WithContext(ctx), fetch)
+	}
+
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.result()
+	case <-req.Context().Done():
+		g.leave(f)
+
+		return nil, nil, req.Context().Err()
+	}
+}
+
+func (g *coalescer) run(f *flight, req *native.Request, fetch fetchFunc) {
+	defer f.cancel()
+
+	f.resp, f.body, f.err = fetch(req)
+
+	g.mu.Lock()
+	g.forget(f)
+	g.mu.Unlock()
+
+	close(f.done)
+}
+
+// leave stops waiting for f, canceling it when nobody is left waiting.
+func (g *coalescer) leave(f *flight) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if f.waiters--; f.waiters > 0 {
+		return
+	}
+
+	// later requests start a new flight instead of joining a canceled one
+	g.forget(f)
+	f.cancel()
+}
+
+func (g *coalescer) forget(f *flight) {
+	if g.flights[f.key] == f {
+		delete(g.flights, f.key)
+	}
+}
+
+func (g *coalescer) key(req *native.Request) string {
+	var key strings.Builder
+
+	key.WriteString(req.Method)
+	key.WriteString(" ")
+	key.WriteString(req.URL.String())
+
+	for _, name := range g.headers {
+		key.WriteString("\n")
+		key.WriteString(name)
+		key.WriteString(": ")
+		key.WriteString(strings.Join(req.Header.Values(name), ", "))
+	}
+
+	return key.String()
+}
+
+// result gives each caller its own copy of the response headers, the body
+// bytes are shared and only ever read.
+func (f *flight) result() (*native.Response, []byte, error) {
+	if f.err != nil {
+		return nil, nil, f.err
+	}
+
+	resp := *f.resp
+	resp.Header = f.resp.Header.Clone()
+
+	return &resp, f.body, nil
+}
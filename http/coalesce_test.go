@@ -0,0 +1,184 @@
+package http_test
+
+import (
+	"context"
+	native "net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/http"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestClient_Coalescing(t *testing.T) {
+	t.Parallel()
+
+	Convey("WithCoalescing", t, func() {
+		ctx := context.Background()
+		release := make(chan struct{})
+		upstream := make(chan *native.Request, 10)
+		status := native.StatusOK
+
+		var calls atomic.Int32
+
+		nativeMock := &http.NativeMock{}
+		nativeMock.On("Do", mock.Anything).Return(func(req *native.Request) (*native.Response, error) {
+			calls.Add(1)
+			upstream <- req
+
+			select {
+			case <-release:
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+
+			if status != native.StatusOK {
+				return newResponse(status, nil), nil
+			}
+
+			return newResponse(status, map[string]string{"name": "gorps"}), nil
+		})
+
+		c := http.NewClient(nativeMock, encoder.NewFactory(), http.WithCoalescing("Accept-Language"))
+
+		type result struct {
+			value map[string]string
+			err   error
+		}
+
+		// concurrently starts the requests and, once they are all waiting,
+		// lets the upstream calls finish.
+		concurrently := func(brokers ...http.RequestBroker) []result {
+			results := make([]result, len(brokers))
+
+			var wg sync.WaitGroup
+
+			for i, broker := range brokers {
+				wg.Add(1)
+
+				go func(i int, broker http.RequestBroker) {
+					defer wg.Done()
+
+					results[i].err = broker.DoAndUnmarshal(ctx, &results[i].value)
+				}(i, broker)
+			}
+
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			return results
+		}
+		get := func() http.RequestBroker {
+			return http.NewRequest(c).URL("https://a.com/users")
+		}
+
+		Convey("should send identical requests once", func() {
+			results := concurrently(get(), get(), get(), get())
+
+			So(calls.Load(), ShouldEqual, 1)
+
+			for _, res := range results {
+				So(res.err, ShouldBeNil)
+				So(res.value, ShouldResemble, map[string]string{"name": "gorps"})
+			}
+
+			results[0].value["name"] = "changed"
+
+			So(results[1].value["name"], ShouldEqual, "gorps")
+		})
+		Convey("should share error statuses", func() {
+			status = native.StatusBadGateway
+			results := concurrently(get(), get())
+
+			So(calls.Load(), ShouldEqual, 1)
+			So(results[0].err, ShouldBeError, "502: : bad requestBroker")
+			So(results[1].err, ShouldBeError, "502: : bad requestBroker")
+		})
+		Convey("should send requests apart when", func() {
+			Convey("a selected header differs", func() {
+				concurrently(get().Header("Accept-Language", "en"), get().Header("Accept-Language", "de"))
+
+				So(calls.Load(), ShouldEqual, 2)
+			})
+			Convey("the authorization differs", func() {
+				concurrently(get().Header("Authorization", "Bearer a"), get().Header("Authorization", "Bearer b"))
+
+				So(calls.Load(), ShouldEqual, 2)
+			})
+			Convey("the urls differ", func() {
+				concurrently(get(), http.NewRequest(c).URL("https://a.com/orders"))
+
+				So(calls.Load(), ShouldEqual, 2)
+			})
+			Convey("the method is not safe", func() {
+				concurrently(get().Post(), get().Post())
+
+				So(calls.Load(), ShouldEqual, 2)
+			})
+			Convey("the request has its own authenticator", func() {
+				auth := http.BearerToken("token")
+				concurrently(get().Auth(auth), get().Auth(auth))
+
+				So(calls.Load(), ShouldEqual, 2)
+			})
+			Convey("coalescing is off", func() {
+				c = http.NewClient(nativeMock, encoder.NewFactory())
+				concurrently(get(), get())
+
+				So(calls.Load(), ShouldEqual, 2)
+			})
+		})
+		Convey("when a caller gives up", func() {
+			canceled, cancel := context.WithCancel(ctx)
+			errs := make(chan error, 1)
+
+			go func() {
+				errs <- get().DoAndUnmarshal(canceled, &struct{}{})
+			}()
+
+			sent := <-upstream
+
+			Convey("should keep the call going for the others", func() {
+				var value map[string]string
+
+				done := make(chan error, 1)
+
+				go func() {
+					done <- get().DoAndUnmarshal(ctx, &value)
+				}()
+
+				time.Sleep(50 * time.Millisecond)
+				cancel()
+
+				So(<-errs, ShouldEqual, context.Canceled)
+				So(sent.Context().Err(), ShouldBeNil)
+
+				close(release)
+
+				So(<-done, ShouldBeNil)
+				So(value["name"], ShouldEqual, "gorps")
+				So(calls.Load(), ShouldEqual, 1)
+			})
+			Convey("should cancel the call once nobody waits for it", func() {
+				cancel()
+
+				So(<-errs, ShouldEqual, context.Canceled)
+
+				<-sent.Context().Done()
+
+				Convey("and start a new one for later requests", func() {
+					close(release)
+
+					So(get().DoAndUnmarshal(ctx, &struct{}{}), ShouldBeNil)
+					So(calls.Load(), ShouldEqual, 2)
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,247 @@
+package http
+
+import (
+	"context"
+	"io"
+	native "net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HedgeOption configures a Hedger.
+type HedgeOption func(h *Hedger)
+
+// Hedger cuts tail latency by sending a second attempt of a request that has
+// not been answered after a delay. Whichever attempt answers first is used and
+// the other one is canceled. Only idempotent requests without a body or with
+// one held in memory are hedged, streamed bodies can't be sent twice at once.
+type Hedger struct {
+	delay    time.Duration
+	quantile float64
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+	full      bool
+}
+
+// NewHedger sends the second attempt after delay.
+func NewHedger(delay time.Duration, opts ...HedgeOption) *Hedger {
+	if delay <= 0 {
+		panic("hedge delay must be positive")
+	}
+
+	h := &Hedger{delay: delay}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// WithLatencyPercentile sends the second attempt once a request has taken
+// longer than quantile (e.g. 0.95) of the last samples answered requests.
+// Until that many were seen the fixed delay is used.
+func WithLatencyPercentile(quantile float64, samples int) HedgeOption {
+	if quantile <= 0 || quantile > 1 || samples <= 0 {
+		panic("quantile must be in (0, 1] and samples positive")
+	}
+
+	return func(h *Hedger) {
+		h.quantile = quantile
+		h.latencies = make([]time.Duration, samples)
+	}
+}
+
+// Delay returns how long a request currently waits before it is hedged.
+func (h *Hedger) Delay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.quantile == 0 || !h.full {
+		return h.delay
+	}
+
+	sorted := append([]time.Duration(nil), h.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	index := int(h.quantile*float64(len(sorted)) + 0.5)
+
+	return sorted[min(max(index-1, 0), len(sorted)-1)]
+}
+
+func (h *Hedger) observe(latency time.Duration) {
+	if h.quantile == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % len(h.latencies)
+	h.full = h.full || h.next == 0
+}
+
+type attempt struct {
+	index   int
+	resp    *native.Response
+	err     error
+	latency time.Duration
+}
+
+// do sends first and, if it is still unanswered after the delay, a copy of
+// it. A failed attempt is only returned once the other one failed as well.
+func (h *Hedger) do(first *native.Request, send func(*native.Request) (*native.Response, error)) (*native.Response, error) {
+	// copy before sending, first is handed to the transport and owned by it
+	second := first.Clone(first.Context())
+	results := make(chan attempt, 2)
+	cancels := make([]context.CancelFunc, 0, 2)
+
+	launch := func(req *native.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		start := time.Now()
+
+		go func() {
+			resp, err := send(req.WithContext(ctx))
+			results <- attempt{index: index, resp: resp, err: err, latency: time.Since(start)}
+		}()
+	}
+
+	launch(first)
+
+	timer := time.NewTimer(h.Delay())
+	defer timer.Stop()
+
+	hedge := timer.C
+	pending := 1
+
+	for {
+		select {
+		case <-hedge:
+			hedge = nil
+
+			if err := reopenBody(second); err != nil {
+				continue
+			}
+
+			pending++
+
+			launch(second)
+		case res := <-results:
+			pending--
+
+			if res.err != nil && pending > 0 {
+				cancels[res.index]()
+
+				continue
+			}
+
+			for i, cancel := range cancels {
+				if i != res.index {
+					cancel()
+				}
+			}
+
+			if pending > 0 {
+				go discard(results)
+			}
+
+			return h.won(res, cancels[res.index])
+		}
+	}
+}
+
+// won returns the response of the winning attempt, keeping its context alive
+// until the body is closed.
+func (h *Hedger) won(res attempt, cancel context.CancelFunc) (*native.Response, error) {
+	if res.err != nil {
+		cancel()
+
+		return nil, res.err
+	}
+
+	h.observe(res.latency)
+
+	if res.resp.Body == nil {
+		cancel()
+
+		return res.resp, nil
+	}
+
+	res.resp.Body = hedgedBody{ReadCloser: res.resp.Body, cancel: cancel}
+
+	return res.resp, nil
+}
+
+// discard closes the response of the attempt that lost.
+func discard(results chan attempt) {
+	res := <-results
+	if res.resp != nil && res.resp.Body != nil {
+		_ = res.resp.Body.Close()
+	}
+}
+
+type hedgedBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b hedgedBody) Close() error {
+	defer b.cancel()
+
+	return b.ReadCloser.Close()
+}
+
+// concurrentBody reports whether req can be sent twice at the same time, which
+// holds for requests without a body and with one held in memory.
+func concurrentBody(req *native.Request) bool {
+	if req.Body == nil || req.Body == native.NoBody {
+		return true
+	}
+
+	_, ok := req.Body.(bytesBody)
+
+	return ok && req.GetBody != nil
+}
+
+// reopenBody gives req a body of its own, only once it is about to be sent.
+func reopenBody(req *native.Request) error {
+	if req.Body == nil || req.Body == native.NoBody {
+		return nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+
+	req.Body = body
+
+	return nil
+}
+
+// isIdempotent reports whether sending a request twice has the same effect as
+// sending it once (RFC 9110 section 9.2.2).
+func isIdempotent(method string) bool {
+	return isSafe(method) || method == native.MethodPut || method == native.MethodDelete
+}
+
+type hedgerKey struct{}
+
+// withHedger stores the hedger of a single request for the Client to apply.
+func withHedger(ctx context.Context, h *Hedger) context.Context {
+	return context.WithValue(ctx, hedgerKey{}, h)
+}
+
+func hedgerFrom(ctx context.Context, fallback *Hedger) *Hedger {
+	if h, ok := ctx.Value(hedgerKey{}).(*Hedger); ok {
+		return h
+	}
+
+	return fallback
+}
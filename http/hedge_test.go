@@ -0,0 +1,245 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	native "net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/http"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewHedger(t *testing.T) {
+	t.Parallel()
+
+	Convey("NewHedger", t, func() {
+		Convey("should panic when the delay is not positive", func() {
+			So(func() { http.NewHedger(0) }, ShouldPanicWith, "hedge delay must be positive")
+		})
+		Convey("should panic when the quantile is out of range", func() {
+			So(func() { http.WithLatencyPercentile(1.5, 10) }, ShouldPanicWith,
+				"quantile must be in (0, 1] and samples positive")
+		})
+		Convey("should return error when the hedger is nil", func() {
+			_, err := http.NewRequest(&http.ClientMock{}).Hedge(nil).CreateRequest(context.Background())
+
+			So(err, ShouldBeError, "hedger is nil")
+		})
+	})
+}
+
+func TestHedger(t *testing.T) {
+	t.Parallel()
+
+	Convey("Hedge", t, func() {
+		ctx := context.Background()
+		hedger := http.NewHedger(20 * time.Millisecond)
+
+		var (
+			calls  atomic.Int32
+			mu     sync.Mutex
+			sent   []*native.Request
+			bodies []string
+		)
+
+		// answer decides how the nth attempt is answered, it is slow unless told
+		// otherwise.
+		answer := func(_ int, req *native.Request) (*native.Response, error) {
+			<-req.Context().Done()
+
+			return nil, req.Context().Err()
+		}
+
+		nativeMock := &http.NativeMock{}
+		nativeMock.On("Do", mock.Anything).Return(func(req *native.Request) (*native.Response, error) {
+			n := int(calls.Add(1))
+
+			var body []byte
+			if req.Body != nil {
+				body, _ = io.ReadAll(req.Body)
+			}
+
+			mu.Lock()
+			sent = append(sent, req)
+			bodies = append(bodies, string(body))
+			mu.Unlock()
+
+			return answer(n, req)
+		})
+
+		c := http.NewClient(nativeMock, encoder.NewFactory())
+		call := func(broker http.RequestBroker) (map[string]int, error) {
+			var dst map[string]int
+
+			err := broker.Hedge(hedger).DoAndUnmarshal(ctx, &dst)
+
+			return dst, err
+		}
+		get := func() http.RequestBroker {
+			return http.NewRequest(c).URL("https://a.com/users")
+		}
+
+		Convey("when the first attempt is slow", func() {
+			answer = func(n int, req *native.Request) (*native.Response, error) {
+				if n == 1 {
+					<-req.Context().Done()
+
+					return nil, req.Context().Err()
+				}
+
+				return newResponse(native.StatusOK, map[string]int{"attempt": n}), nil
+			}
+
+			Convey("should use the second attempt and cancel the first", func() {
+				start := time.Now()
+				dst, err := call(get())
+
+				So(err, ShouldBeNil)
+				So(dst["attempt"], ShouldEqual, 2)
+				So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 20*time.Millisecond)
+
+				mu.Lock()
+				first := sent[0]
+				mu.Unlock()
+
+				<-first.Context().Done()
+
+				So(calls.Load(), ShouldEqual, 2)
+			})
+			Convey("should send the body again", func() {
+				_, err := call(get().Put().Body(`{"name":"gorps"}`))
+
+				So(err, ShouldBeNil)
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				So(bodies, ShouldResemble, []string{`{"name":"gorps"}`, `{"name":"gorps"}`})
+			})
+			Convey("should not hedge a streamed body", func() {
+				answer = func(n int, _ *native.Request) (*native.Response, error) {
+					time.Sleep(40 * time.Millisecond)
+
+					return newResponse(native.StatusOK, map[string]int{"attempt": n}), nil
+				}
+				payload := strings.Repeat("a", 100)
+
+				dst, err := call(get().Put().BodyReader(bytes.NewReader([]byte(payload))))
+
+				So(err, ShouldBeNil)
+				So(dst["attempt"], ShouldEqual, 1)
+				So(calls.Load(), ShouldEqual, 1)
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				So(bodies, ShouldResemble, []string{payload})
+			})
+			Convey("should use the Client hedger by default", func() {
+				c = http.NewClient(nativeMock, encoder.NewFactory(), http.WithHedging(hedger))
+
+				var dst map[string]int
+
+				So(get().DoAndUnmarshal(ctx, &dst), ShouldBeNil)
+				So(dst["attempt"], ShouldEqual, 2)
+			})
+			Convey("should not hedge methods that are not idempotent", func() {
+				answer = func(n int, req *native.Request) (*native.Response, error) {
+					time.Sleep(40 * time.Millisecond)
+
+					return newResponse(native.StatusOK, map[string]int{"attempt": n}), nil
+				}
+
+				dst, err := call(get().Post())
+
+				So(err, ShouldBeNil)
+				So(dst["attempt"], ShouldEqual, 1)
+				So(calls.Load(), ShouldEqual, 1)
+			})
+		})
+		Convey("should not hedge when the first attempt is fast", func() {
+			answer = func(n int, _ *native.Request) (*native.Response, error) {
+				return newResponse(native.StatusOK, map[string]int{"attempt": n}), nil
+			}
+
+			dst, err := call(get())
+			time.Sleep(40 * time.Millisecond)
+
+			So(err, ShouldBeNil)
+			So(dst["attempt"], ShouldEqual, 1)
+			So(calls.Load(), ShouldEqual, 1)
+		})
+		Convey("should wait for the other attempt when one fails", func() {
+			answer = func(n int, _ *native.Request) (*native.Response, error) {
+				time.Sleep(30 * time.Millisecond)
+
+				if n == 1 {
+					return nil, errors.New("connection reset")
+				}
+
+				return newResponse(native.StatusOK, map[string]int{"attempt": n}), nil
+			}
+
+			dst, err := call(get())
+
+			So(err, ShouldBeNil)
+			So(dst["attempt"], ShouldEqual, 2)
+		})
+		Convey("should fail when the first attempt fails before hedging", func() {
+			answer = func(int, *native.Request) (*native.Response, error) {
+				return nil, errors.New("connection refused")
+			}
+
+			_, err := call(get())
+
+			So(err, ShouldBeError, "connection refused")
+			So(calls.Load(), ShouldEqual, 1)
+		})
+		Convey("should fail when both attempts fail", func() {
+			answer = func(int, *native.Request) (*native.Response, error) {
+				time.Sleep(30 * time.Millisecond)
+
+				return nil, errors.New("connection reset")
+			}
+
+			_, err := call(get())
+
+			So(err, ShouldBeError, "connection reset")
+			So(calls.Load(), ShouldEqual, 2)
+		})
+		Convey("should stop when the caller gives up", func() {
+			canceled, cancel := context.WithTimeout(ctx, 60*time.Millisecond)
+			defer cancel()
+
+			err := get().Hedge(hedger).DoAndUnmarshal(canceled, &struct{}{})
+
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+			So(calls.Load(), ShouldEqual, 2)
+		})
+		Convey("should hedge at the latency percentile once enough requests were seen", func() {
+			hedger = http.NewHedger(time.Second, http.WithLatencyPercentile(0.5, 4))
+			answer = func(n int, _ *native.Request) (*native.Response, error) {
+				return newResponse(native.StatusOK, map[string]int{"attempt": n}), nil
+			}
+
+			So(hedger.Delay(), ShouldEqual, time.Second)
+
+			for i := 0; i < 4; i++ {
+				_, err := call(get())
+				So(err, ShouldBeNil)
+			}
+
+			So(hedger.Delay(), ShouldBeLessThan, 100*time.Millisecond)
+		})
+	})
+}
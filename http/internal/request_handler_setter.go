@@ -4,15 +4,23 @@ import (
 	"io"
 	"net/http"
 	"reflect"
+	"strings"
 
+	"github.com/kevinanthony/gorps/v2/compress"
 	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/header"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
 )
 
 const (
 	base10  = 10
 	bitSize = 64
+
+	// DefaultMaxDecompressedSize caps how large a compressed request body may
+	// grow once decompressed, so a small zip bomb can't exhaust memory.
+	DefaultMaxDecompressedSize = 10 << 20
 )
 
 type RequestHandlerSetter interface {
@@ -22,22 +30,41 @@ type RequestHandlerSetter interface {
 	Query(value reflect.Value, req *http.Request, query string) error
 }
 
+// SetterOption configures a RequestHandlerSetter.
+type SetterOption func(r *requestHandlerSetter)
+
 type requestHandlerSetter struct {
-	factory encoder.Factory
+	factory             encoder.Factory
+	maxDecompressedSize int64
 }
 
-func NewRequestHandlerSetter(factory encoder.Factory) RequestHandlerSetter {
+func NewRequestHandlerSetter(factory encoder.Factory, opts ...SetterOption) RequestHandlerSetter {
 	if factory == nil {
 		panic("encoder factory is required")
 	}
 
-	return &requestHandlerSetter{
-		factory: factory,
+	r := &requestHandlerSetter{
+		factory:             factory,
+		maxDecompressedSize: DefaultMaxDecompressedSize,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// WithMaxDecompressedSize sets the limit for compressed request bodies once
+// they are decompressed.
+func WithMaxDecompressedSize(size int64) SetterOption {
+	return func(r *requestHandlerSetter) {
+		r.maxDecompressedSize = size
 	}
 }
 
 func (r requestHandlerSetter) Body(value reflect.Value, req *http.Request) error {
-	bts, err := io.ReadAll(req.Body)
+	bts, err := r.readBody(req)
 	if err != nil {
 		return err
 	}
@@ -45,6 +72,36 @@ func (r requestHandlerSetter) Body(value reflect.Value, req *http.Request) error
 	return r.setStruct(value, r.factory.CreateFromRequest(req), bts)
 }
 
+func (r requestHandlerSetter) readBody(req *http.Request) ([]byte, error) {
+	encoding := req.Header.Get(header.ContentEncoding)
+	if len(encoding) == 0 || strings.EqualFold(encoding, compress.Identity) {
+		return io.ReadAll(req.Body)
+	}
+
+	comp, ok := compress.FromEncoding(encoding)
+	if !ok {
+		return nil, errors.Errorf("unsupported content encoding: %s", encoding)
+	}
+
+	reader, err := comp.NewReader(req.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decompress %s", encoding)
+	}
+
+	defer reader.Close()
+
+	bts, err := io.ReadAll(io.LimitReader(reader, r.maxDecompressedSize+1))
+	if err != nil {
+		return nil, errors.Wrapf(err, "decompress %s", encoding)
+	}
+
+	if int64(len(bts)) > r.maxDecompressedSize {
+		return nil, errors.Errorf("decompressed body exceeds %d bytes", r.maxDecompressedSize)
+	}
+
+	return bts, nil
+}
+
 func (r requestHandlerSetter) Header(value reflect.Value, req *http.Request, header string) error {
 	headerStr := req.Header.Get(header)
 
@@ -1,6 +1,8 @@
 package internal_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"errors"
 	"io"
@@ -10,6 +12,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kevinanthony/gorps/v2/encoder"
 	mocks "github.com/kevinanthony/gorps/v2/http"
@@ -64,6 +67,26 @@ func TestRequestHandlerSetter_Body(t *testing.T) {
 					So(actual.Body, ShouldResemble, expected.Body)
 					mock.AssertExpectationsForObjects(t, bag...)
 				})
+				Convey("request is CBOR", func() {
+					req := httptest.NewRequest(http.MethodGet, "/", testx.ToReadCloser(encoder.NewCBOR(), expected.Body))
+					factory.On("CreateFromRequest", req).Return(encoder.NewCBOR()).Once()
+
+					err := setter.Body(valueOf, req)
+
+					So(err, ShouldBeNil)
+					So(actual.Body, ShouldResemble, expected.Body)
+					mock.AssertExpectationsForObjects(t, bag...)
+				})
+				Convey("request is MsgPack", func() {
+					req := httptest.NewRequest(http.MethodGet, "/", testx.ToReadCloser(encoder.NewMsgPack(), expected.Body))
+					factory.On("CreateFromRequest", req).Return(encoder.NewMsgPack()).Once()
+
+					err := setter.Body(valueOf, req)
+
+					So(err, ShouldBeNil)
+					So(actual.Body, ShouldResemble, expected.Body)
+					mock.AssertExpectationsForObjects(t, bag...)
+				})
 				Convey("request is XML", func() {
 					req := httptest.NewRequest(http.MethodGet, "/", testx.ToReadCloser(encoder.NewXML(), expected.Body))
 					factory.On("CreateFromRequest", req).Return(encoder.NewXML()).Once()
@@ -75,7 +98,62 @@ func TestRequestHandlerSetter_Body(t *testing.T) {
 					mock.AssertExpectationsForObjects(t, bag...)
 				})
 			})
+			Convey("should decompress body", func() {
+				Convey("when content encoding is gzip", func() {
+					req := httptest.NewRequest(http.MethodPost, "/", gzipReadCloser(testx.ToReadCloser(encoder.NewJSON(), expected.Body)))
+					req.Header.Set("Content-Encoding", "gzip")
+					factory.On("CreateFromRequest", req).Return(encoder.NewJSON()).Once()
+
+					err := setter.Body(valueOf, req)
+
+					So(err, ShouldBeNil)
+					So(actual.Body, ShouldResemble, expected.Body)
+					mock.AssertExpectationsForObjects(t, bag...)
+				})
+				Convey("when content encoding is identity", func() {
+					req := httptest.NewRequest(http.MethodPost, "/", testx.ToReadCloser(encoder.NewJSON(), expected.Body))
+					req.Header.Set("Content-Encoding", "identity")
+					factory.On("CreateFromRequest", req).Return(encoder.NewJSON()).Once()
+
+					err := setter.Body(valueOf, req)
+
+					So(err, ShouldBeNil)
+					So(actual.Body, ShouldResemble, expected.Body)
+					mock.AssertExpectationsForObjects(t, bag...)
+				})
+			})
 			Convey("should return error when", func() {
+				Convey("decompressed body is too large", func() {
+					setter := internal.NewRequestHandlerSetter(factory, internal.WithMaxDecompressedSize(16))
+					req := httptest.NewRequest(http.MethodPost, "/", gzipReadCloser(io.NopCloser(strings.NewReader(strings.Repeat("a", 1024)))))
+					req.Header.Set("Content-Encoding", "gzip")
+
+					err := setter.Body(valueOf, req)
+
+					So(err, ShouldBeError, "decompressed body exceeds 16 bytes")
+					So(actual.Body, ShouldBeNil)
+					mock.AssertExpectationsForObjects(t, bag...)
+				})
+				Convey("content encoding is not supported", func() {
+					req := httptest.NewRequest(http.MethodPost, "/", testx.ToReadCloser(encoder.NewJSON(), expected.Body))
+					req.Header.Set("Content-Encoding", "compress")
+
+					err := setter.Body(valueOf, req)
+
+					So(err, ShouldBeError, "unsupported content encoding: compress")
+					So(actual.Body, ShouldBeNil)
+					mock.AssertExpectationsForObjects(t, bag...)
+				})
+				Convey("body is not compressed as advertised", func() {
+					req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("this is definitely not gzip")))
+					req.Header.Set("Content-Encoding", "gzip")
+
+					err := setter.Body(valueOf, req)
+
+					So(err, ShouldBeError, "decompress gzip: gzip: invalid header")
+					So(actual.Body, ShouldBeNil)
+					mock.AssertExpectationsForObjects(t, bag...)
+				})
 				Convey("io reader fails", func() {
 					reader.On("Read", mock.Anything).Return(0, errors.New("everybody body mock"))
 
@@ -127,6 +205,21 @@ func TestRequestHandlerSetter_Body(t *testing.T) {
 	})
 }
 
+func gzipReadCloser(body io.ReadCloser) io.ReadCloser {
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+	if _, err := io.Copy(w, body); err != nil {
+		panic(err)
+	}
+
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+
+	return io.NopCloser(&buf)
+}
+
 func TestRequestHandlerSetter_Header(t *testing.T) {
 	t.Parallel()
 
@@ -594,6 +687,34 @@ func TestRequestHandlerSetter_Query(t *testing.T) {
 				mock.AssertExpectationsForObjects(t, bag...)
 			})
 		})
+		Convey("text", func() {
+			var dst struct {
+				Since time.Time
+				Until *time.Time
+			}
+
+			since := reflect.ValueOf(&dst).Elem().Field(0)
+			until := reflect.ValueOf(&dst).Elem().Field(1)
+
+			Convey("when Query is text", func() {
+				q.Set("since", "1989-11-09T18:01:00Z")
+				req.URL.RawQuery = q.Encode()
+
+				So(setter.Query(since, req, "since"), ShouldBeNil)
+				So(setter.Query(until, req, "since"), ShouldBeNil)
+				So(dst.Since, ShouldEqual, time.Date(1989, 11, 9, 18, 1, 0, 0, time.UTC))
+				So(*dst.Until, ShouldEqual, dst.Since)
+			})
+			Convey("when Query is not valid text", func() {
+				q.Set("since", "yesterday")
+				req.URL.RawQuery = q.Encode()
+
+				err := setter.Query(since, req, "since")
+
+				So(err, ShouldBeError, `unmarshal time.Time: parsing time "yesterday" as "2006-01-02T15:04:05Z07:00": `+
+					`cannot parse "yesterday" as "2006"`)
+			})
+		})
 	})
 }
 
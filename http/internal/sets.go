@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"encoding"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -10,12 +11,18 @@ import (
 	"github.com/pkg/errors"
 )
 
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
 //nolint:cyclop // this is just a big switch, nothing complex
 func (r requestHandlerSetter) set(value reflect.Value, str string) error {
 	if len(str) == 0 {
 		return nil
 	}
 
+	if ok, err := r.setText(value, str); ok {
+		return err
+	}
+
 	switch value.Interface().(type) {
 	case int, int8, int16, int32, int64:
 		return r.setInt(value, str)
@@ -44,6 +51,35 @@ func (r requestHandlerSetter) set(value reflect.Value, str string) error {
 	}
 }
 
+// setText sets values whose type reads itself from text, such as time.Time,
+// the way the client writes them. It reports false for any other type.
+func (r requestHandlerSetter) setText(value reflect.Value, str string) (bool, error) {
+	if value.Kind() == reflect.Ptr {
+		if !value.Type().Implements(textUnmarshalerType) || !value.CanSet() {
+			return false, nil
+		}
+
+		dst := reflect.New(value.Type().Elem())
+		if err := dst.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(str)); err != nil {
+			return true, errors.Wrapf(err, "unmarshal %s", value.Type())
+		}
+
+		value.Set(dst)
+
+		return true, nil
+	}
+
+	if !value.CanAddr() || !value.Addr().Type().Implements(textUnmarshalerType) {
+		return false, nil
+	}
+
+	if err := value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(str)); err != nil {
+		return true, errors.Wrapf(err, "unmarshal %s", value.Type())
+	}
+
+	return true, nil
+}
+
 func (r requestHandlerSetter) setStruct(value reflect.Value, enc encoder.Encoder, bts []byte) error {
 	if !value.IsValid() {
 		return errors.New("bad body value")
@@ -62,7 +98,7 @@ func (r requestHandlerSetter) setStruct(value reflect.Value, enc encoder.Encoder
 
 	dst := reflect.New(typeOf).Interface()
 
-	if err := enc.Decode(bts, &dst); err != nil {
+	if err := enc.Decode(bts, dst); err != nil {
 		return errors.Wrapf(err, "decode %s", enc.GetMime())
 	}
 
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+)
+
+//go:generate mockery --name=Iterator --structname=IteratorMock --filename=iterator_mock.go --inpackage
+type Iterator interface {
+	Next() bool
+	Decode(v interface{}) error
+	Err() error
+	Close() error
+}
+
+// lineIterator reads a newline delimited body one record at a time, so only
+// the current line is ever held in memory.
+type lineIterator struct {
+	reader *bufio.Reader
+	body   io.Reader
+	enc    encoder.Encoder
+	line   []byte
+	err    error
+}
+
+func newLineIterator(body io.Reader, enc encoder.Encoder) Iterator {
+	return &lineIterator{
+		reader: bufio.NewReader(body),
+		body:   body,
+		enc:    enc,
+	}
+}
+
+func (l *lineIterator) Next() bool {
+	for l.err == nil {
+		line, err := l.reader.ReadBytes('\n')
+		if err != nil && err != io.EOF { //nolint:errorlint // io.EOF is never wrapped by bufio
+			l.err = err
+
+			return false
+		}
+
+		l.line = bytes.TrimSpace(line)
+		if len(l.line) > 0 {
+			return true
+		}
+
+		if err == io.EOF { //nolint:errorlint // io.EOF is never wrapped by bufio
+			return false
+		}
+	}
+
+	return false
+}
+
+func (l *lineIterator) Decode(dst interface{}) error {
+	return l.enc.Decode(l.line, dst)
+}
+
+func (l *lineIterator) Err() error {
+	return l.err
+}
+
+func (l *lineIterator) Close() error {
+	if closer, ok := l.body.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
@@ -12,6 +12,10 @@ const (
 	MethodPost    = http.MethodPost
 	MethodGet     = http.MethodGet
 	MethodPut     = http.MethodPut
+	MethodPatch   = http.MethodPatch
 	MethodDelete  = http.MethodDelete
+	MethodHead    = http.MethodHead
 	MethodOptions = http.MethodOptions
+	MethodConnect = http.MethodConnect
+	MethodTrace   = http.MethodTrace
 )
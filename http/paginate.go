@@ -0,0 +1,335 @@
+package http
+
+import (
+	"context"
+	native "net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//go:generate mockery --name=PageIterator --structname=PageIteratorMock --filename=page_iterator_mock.go --inpackage
+type PageIterator interface {
+	Next() bool
+	Page() *Response
+	Err() error
+}
+
+// Pagination works out the request for the page after resp. It returns a nil
+// request once resp was the last page.
+type Pagination interface {
+	Next(req *native.Request, resp *Response) (*native.Request, error)
+}
+
+// PaginationFunc lets an ordinary function be used as a Pagination.
+type PaginationFunc func(req *native.Request, resp *Response) (*native.Request, error)
+
+func (f PaginationFunc) Next(req *native.Request, resp *Response) (*native.Request, error) {
+	return f(req, resp)
+}
+
+// CountFunc returns the number of items in a decoded page.
+type CountFunc func(page interface{}) int
+
+// PaginateOption configures Paginate.
+type PaginateOption func(p *pageIterator)
+
+// MaxPages stops paginating after n pages.
+func MaxPages(n int) PaginateOption {
+	return func(p *pageIterator) {
+		p.maxPages = n
+	}
+}
+
+// LinkNext follows the rel="next" url of the Link header (RFC 8288) and stops
+// when there is none.
+func LinkNext() Pagination {
+	return PaginationFunc(func(req *native.Request, resp *Response) (*native.Request, error) {
+		next, ok := linkNext(resp.Header.Values("Link"))
+		if !ok {
+			return nil, nil
+		}
+
+		u, err := resp.URL.Parse(next)
+		if err != nil {
+			return nil, errors.Wrap(err, "link header")
+		}
+
+		return nextRequest(req, u), nil
+	})
+}
+
+// Cursor sets query param to the cursor cursor finds in the decoded page, and
+// stops when it returns an empty cursor.
+func Cursor(param string, cursor func(page interface{}) string) Pagination {
+	return PaginationFunc(func(req *native.Request, resp *Response) (*native.Request, error) {
+		next := cursor(resp.Body)
+		if len(next) == 0 {
+			return nil, nil
+		}
+
+		return withQuery(req, param, next), nil
+	})
+}
+
+// PageNumber increments query param, starting from 1 when the first request
+// does not set it, and stops at the first page without items.
+func PageNumber(param string, count CountFunc) Pagination {
+	return PaginationFunc(func(req *native.Request, resp *Response) (*native.Request, error) {
+		if count(resp.Body) == 0 {
+			return nil, nil
+		}
+
+		page, err := queryInt(req, param, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		return withQuery(req, param, strconv.Itoa(page+1)), nil
+	})
+}
+
+// Offset advances query param by the number of items in each page, starting
+// from 0 when the first request does not set it, and stops at the first page
+// without items.
+func Offset(param string, count CountFunc) Pagination {
+	return PaginationFunc(func(req *native.Request, resp *Response) (*native.Request, error) {
+		n := count(resp.Body)
+		if n == 0 {
+			return nil, nil
+		}
+
+		offset, err := queryInt(req, param, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		return withQuery(req, param, strconv.Itoa(offset+n)), nil
+	})
+}
+
+// SliceLen counts the items of a page that decodes into a slice.
+func SliceLen(page interface{}) int {
+	v := reflect.Indirect(reflect.ValueOf(page))
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return 0
+	}
+
+	return v.Len()
+}
+
+type pageIterator struct {
+	ctx      context.Context
+	client   Client
+	strategy Pagination
+	pageType reflect.Type
+	maxPages int
+
+	req   *native.Request
+	page  *Response
+	pages int
+	err   error
+}
+
+// Paginate returns an iterator that requests one page per Next, decoding
+// each into a new value of the type v points to. It stops when strategy finds
+// no next page or the page just read again, ctx is done or MaxPages is
+// reached. A next page on another origin is an error, it is not requested.
+func (r *requestBroker) Paginate(ctx context.Context, v interface{}, strategy Pagination,
+	opts ...PaginateOption,
+) PageIterator {
+	p := &pageIterator{
+		ctx:      ctx,
+		client:   r.client,
+		strategy: strategy,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if strategy == nil {
+		p.err = errors.New("paginate: pagination is required")
+
+		return p
+	}
+
+	if t := reflect.TypeOf(v); t == nil || t.Kind() != reflect.Ptr {
+		p.err = errors.Errorf("paginate: expected pointer, got %T", v)
+
+		return p
+	}
+
+	p.pageType = reflect.TypeOf(v).Elem()
+	p.req, p.err = r.CreateRequest(ctx)
+
+	return p
+}
+
+func (p *pageIterator) Next() bool {
+	if p.err != nil {
+		return false
+	}
+
+	if p.page != nil {
+		prev, page := p.req, p.page
+		p.req, p.err = p.strategy.Next(prev, page)
+		p.page = nil
+
+		if p.req != nil && p.err == nil {
+			p.req, p.err = followable(prev, p.req, page.URL)
+		}
+	}
+
+	if p.req == nil || p.err != nil || (p.maxPages > 0 && p.pages >= p.maxPages) {
+		p.req = nil
+
+		return false
+	}
+
+	if p.err = p.ctx.Err(); p.err != nil {
+		return false
+	}
+
+	page, err := p.client.DoResponse(p.req, reflect.New(p.pageType).Interface())
+	if err != nil {
+		p.err = err
+
+		return false
+	}
+
+	if page.Body == nil {
+		page.Body = reflect.New(p.pageType).Interface()
+	}
+
+	p.page = page
+	p.pages++
+
+	return true
+}
+
+// Page returns the page read by the last call to Next, with Body set to the
+// decoded page.
+func (p *pageIterator) Page() *Response {
+	return p.page
+}
+
+func (p *pageIterator) Err() error {
+	return p.err
+}
+
+func linkNext(links []string) (string, bool) {
+	for _, value := range links {
+		for _, link := range splitLinks(value) {
+			target, params, found := strings.Cut(link, ">")
+			if !found || !strings.HasPrefix(target, "<") {
+				continue
+			}
+
+			for _, param := range strings.Split(params, ";") {
+				key, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(strings.TrimSpace(key), "rel") {
+					continue
+				}
+
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(val), `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return strings.TrimSpace(target[1:]), true
+					}
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+// splitLinks splits a Link header into its links (RFC 8288 section 3). Commas
+// inside a <target> or a quoted parameter belong to the link they are in.
+func splitLinks(value string) []string {
+	var (
+		links    []string
+		start    int
+		inTarget bool
+		inQuotes bool
+	)
+
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case inQuotes:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inQuotes = false
+			}
+		case inTarget:
+			inTarget = c != '>'
+		case c == '<':
+			inTarget = true
+		case c == '"':
+			inQuotes = true
+		case c == ',':
+			links = append(links, strings.TrimSpace(value[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(links, strings.TrimSpace(value[start:]))
+}
+
+func queryInt(req *native.Request, param string, fallback int) (int, error) {
+	value := req.URL.Query().Get(param)
+	if len(value) == 0 {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "query param %s", param)
+	}
+
+	return n, nil
+}
+
+// followable returns next unless it asks for the page that was just read
+// again, which would never end. A page on another origin is refused, next
+// still carries the credentials and signature meant for this one.
+func followable(prev, next *native.Request, current *url.URL) (*native.Request, error) {
+	target := current.ResolveReference(next.URL)
+	if !strings.EqualFold(target.Scheme, current.Scheme) || !strings.EqualFold(target.Host, current.Host) {
+		return nil, errors.Errorf("paginate: next page %s is on another origin", target.Redacted())
+	}
+
+	if target.String() == current.ResolveReference(prev.URL).String() {
+		return nil, nil
+	}
+
+	return next, nil
+}
+
+func withQuery(req *native.Request, param, value string) *native.Request {
+	u := *req.URL
+	query := u.Query()
+	query.Set(param, value)
+	u.RawQuery = query.Encode()
+
+	return nextRequest(req, &u)
+}
+
+// nextRequest copies req for the url of another page.
+func nextRequest(req *native.Request, u *url.URL) *native.Request {
+	next := req.Clone(req.Context())
+	next.URL = u
+	next.Host = u.Host
+
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			next.Body = body
+		}
+	}
+
+	return next
+}
@@ -0,0 +1,224 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	native "net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/http"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRequestBroker_Paginate(t *testing.T) {
+	t.Parallel()
+
+	Convey("Paginate", t, func() {
+		ctx := context.Background()
+		items := []int{1, 2, 3, 4, 5}
+		server := httptest.NewServer(native.HandlerFunc(func(w native.ResponseWriter, r *native.Request) {
+			w.Header().Set("Content-Type", encoder.ApplicationJSON)
+
+			query := r.URL.Query()
+			page, _ := strconv.Atoi(query.Get("page"))
+			offset, _ := strconv.Atoi(query.Get("offset"))
+
+			switch r.URL.Path {
+			case "/link":
+				if page == 0 {
+					page = 1
+				}
+
+				if page < 3 {
+					w.Header().Add("Link", fmt.Sprintf(`</link?page=1>; rel="first", </link?page=%d>; rel="next"`, page+1))
+				}
+
+				_, _ = fmt.Fprintf(w, "[%d]", page)
+			case "/commas":
+				ids := query.Get("ids")
+				if len(ids) == 0 {
+					w.Header().Add("Link", `</commas>; rel="first"; title="ids, all", </commas?ids=1,2>; rel="next"`)
+				}
+
+				_, _ = fmt.Fprintf(w, "[%s]", ids)
+			case "/repeat":
+				w.Header().Add("Link", `</repeat>; rel="next"`)
+				_, _ = w.Write([]byte(`[1]`))
+			case "/elsewhere":
+				w.Header().Add("Link", `<https://other.example/steal?page=2>; rel="next"`)
+				_, _ = w.Write([]byte(`[1]`))
+			case "/stuck":
+				_, _ = w.Write([]byte(`{"items":[1],"next":"a"}`))
+			case "/cursor":
+				switch query.Get("cursor") {
+				case "":
+					_, _ = w.Write([]byte(`{"items":[1,2],"next":"b"}`))
+				case "b":
+					_, _ = w.Write([]byte(`{"items":[3],"next":""}`))
+				}
+			case "/page":
+				if page == 0 {
+					page = 1
+				}
+
+				start := min((page-1)*2, len(items))
+				_, _ = fmt.Fprint(w, toJSON(items[start:min(start+2, len(items))]))
+			case "/offset":
+				_, _ = fmt.Fprint(w, toJSON(items[min(offset, len(items)):min(offset+2, len(items))]))
+			default:
+				w.WriteHeader(native.StatusNotFound)
+			}
+		}))
+
+		Reset(server.Close)
+
+		c := http.NewClient(server.Client(), encoder.NewFactory())
+
+		Convey("should follow Link headers", func() {
+			pages := http.NewRequest(c).URL(server.URL+"/link").Paginate(ctx, &[]int{}, http.LinkNext())
+
+			So(collect(pages), ShouldResemble, [][]int{{1}, {2}, {3}})
+			So(pages.Err(), ShouldBeNil)
+		})
+		Convey("should follow Link headers with commas in the target", func() {
+			pages := http.NewRequest(c).URL(server.URL+"/commas").Paginate(ctx, &[]int{}, http.LinkNext())
+
+			So(collect(pages), ShouldResemble, [][]int{{}, {1, 2}})
+			So(pages.Err(), ShouldBeNil)
+		})
+		Convey("should follow cursors", func() {
+			type page struct {
+				Items []int  `json:"items"`
+				Next  string `json:"next"`
+			}
+
+			pages := http.NewRequest(c).URL(server.URL+"/cursor").Paginate(ctx, &page{},
+				http.Cursor("cursor", func(p interface{}) string { return p.(*page).Next }))
+
+			var actual [][]int
+
+			for pages.Next() {
+				actual = append(actual, pages.Page().Body.(*page).Items)
+			}
+
+			So(actual, ShouldResemble, [][]int{{1, 2}, {3}})
+			So(pages.Err(), ShouldBeNil)
+		})
+		Convey("should count pages until one is empty", func() {
+			pages := http.NewRequest(c).URL(server.URL+"/page").Paginate(ctx, &[]int{},
+				http.PageNumber("page", http.SliceLen))
+
+			So(collect(pages), ShouldResemble, [][]int{{1, 2}, {3, 4}, {5}, {}})
+			So(pages.Err(), ShouldBeNil)
+		})
+		Convey("should advance the offset by the items read", func() {
+			pages := http.NewRequest(c).URL(server.URL+"/offset").Query("offset", "1").Paginate(ctx, &[]int{},
+				http.Offset("offset", http.SliceLen))
+
+			So(collect(pages), ShouldResemble, [][]int{{2, 3}, {4, 5}, {}})
+			So(pages.Err(), ShouldBeNil)
+		})
+		Convey("should stop when the next page is the same page", func() {
+			pages := http.NewRequest(c).URL(server.URL+"/repeat").Paginate(ctx, &[]int{}, http.LinkNext())
+
+			So(collect(pages), ShouldResemble, [][]int{{1}})
+			So(pages.Err(), ShouldBeNil)
+
+			type page struct {
+				Items []int  `json:"items"`
+				Next  string `json:"next"`
+			}
+
+			cursors := http.NewRequest(c).URL(server.URL+"/stuck").Paginate(ctx, &page{},
+				http.Cursor("cursor", func(p interface{}) string { return p.(*page).Next }))
+
+			n := 0
+			for cursors.Next() {
+				n++
+			}
+
+			So(n, ShouldEqual, 2)
+			So(cursors.Err(), ShouldBeNil)
+		})
+		Convey("should stop at max pages", func() {
+			pages := http.NewRequest(c).URL(server.URL+"/link").Paginate(ctx, &[]int{}, http.LinkNext(), http.MaxPages(2))
+
+			So(collect(pages), ShouldResemble, [][]int{{1}, {2}})
+			So(pages.Err(), ShouldBeNil)
+		})
+		Convey("should stop when context is canceled", func() {
+			ctx, cancel := context.WithCancel(ctx)
+			pages := http.NewRequest(c).URL(server.URL+"/link").Paginate(ctx, &[]int{}, http.LinkNext())
+
+			So(pages.Next(), ShouldBeTrue)
+
+			cancel()
+
+			So(pages.Next(), ShouldBeFalse)
+			So(errors.Is(pages.Err(), context.Canceled), ShouldBeTrue)
+		})
+		Convey("should return error", func() {
+			Convey("when a page fails", func() {
+				pages := http.NewRequest(c).URL(server.URL+"/missing").Paginate(ctx, &[]int{}, http.LinkNext())
+
+				So(pages.Next(), ShouldBeFalse)
+				So(pages.Err(), ShouldBeError, "404: : bad requestBroker")
+			})
+			Convey("when the next page is on another origin", func() {
+				var sent []*native.Request
+
+				nativeMock := &http.NativeMock{}
+				nativeMock.On("Do", mock.Anything).Return(func(req *native.Request) (*native.Response, error) {
+					sent = append(sent, req)
+
+					return server.Client().Do(req)
+				})
+
+				c := http.NewClient(nativeMock, encoder.NewFactory())
+				pages := http.NewRequest(c).URL(server.URL+"/elsewhere").Header("Authorization", "Bearer token").
+					Paginate(ctx, &[]int{}, http.LinkNext())
+
+				So(pages.Next(), ShouldBeTrue)
+				So(pages.Next(), ShouldBeFalse)
+				So(pages.Err(), ShouldBeError, "paginate: next page https://other.example/steal?page=2 is on another origin")
+				So(sent, ShouldHaveLength, 1)
+			})
+			Convey("when v is not a pointer", func() {
+				pages := http.NewRequest(c).URL(server.URL+"/link").Paginate(ctx, []int{}, http.LinkNext())
+
+				So(pages.Next(), ShouldBeFalse)
+				So(pages.Err(), ShouldBeError, "paginate: expected pointer, got []int")
+			})
+			Convey("when the page param is not a number", func() {
+				pages := http.NewRequest(c).URL(server.URL+"/page").Query("page", "one").Paginate(ctx, &[]int{},
+					http.PageNumber("page", func(interface{}) int { return 1 }))
+
+				So(pages.Next(), ShouldBeTrue)
+				So(pages.Next(), ShouldBeFalse)
+				So(pages.Err(), ShouldBeError, `query param page: strconv.Atoi: parsing "one": invalid syntax`)
+			})
+		})
+	})
+}
+
+func collect(pages http.PageIterator) [][]int {
+	actual := [][]int{}
+
+	for pages.Next() {
+		actual = append(actual, *pages.Page().Body.(*[]int))
+	}
+
+	return actual
+}
+
+func toJSON(v []int) string {
+	bts, _ := encoder.NewJSON().Encode(v)
+
+	return string(bts)
+}
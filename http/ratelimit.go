@@ -0,0 +1,274 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"math"
+	native "net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	headerRetryAfter         = "Retry-After"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+
+	// resetEpochThreshold tells an X-RateLimit-Reset unix timestamp apart from
+	// a number of seconds, APIs use both.
+	resetEpochThreshold = 1e9
+)
+
+// ErrRateLimited matches every RateLimitError with errors.Is.
+var ErrRateLimited = errors.New("rate limited")
+
+// RateLimitError is returned when a request would have to wait for the rate
+// limiter longer than it is allowed to.
+type RateLimitError struct {
+	Key string
+	// RetryAfter is how long until the request could have been sent.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if len(e.Key) == 0 {
+		return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+	}
+
+	return fmt.Sprintf("rate limited for %s, retry after %s", e.Key, e.RetryAfter)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// LimiterOption configures a RateLimiter.
+type LimiterOption func(l *RateLimiter)
+
+// RateLimiter is a token bucket limiter for the requests of a Client, with one
+// bucket per key. By default there is a single bucket for the whole Client.
+type RateLimiter struct {
+	rate     float64
+	burst    float64
+	key      func(req *native.Request) string
+	failFast bool
+	adaptive bool
+	now      func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// blocked holds requests back until the server said the quota resets.
+	blocked time.Time
+}
+
+// NewRateLimiter allows rate requests per second on average, with bursts of up
+// to burst requests.
+func NewRateLimiter(rate float64, burst int, opts ...LimiterOption) *RateLimiter {
+	if rate <= 0 || burst <= 0 {
+		panic("rate and burst must be positive")
+	}
+
+	l := &RateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		key:     func(*native.Request) string { return "" },
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// WithLimitKey keeps a bucket per key, see LimitPerHost and LimitPerRoute.
+func WithLimitKey(key func(req *native.Request) string) LimiterOption {
+	return func(l *RateLimiter) {
+		l.key = key
+	}
+}
+
+// LimitPerHost keeps a bucket per host.
+func LimitPerHost(req *native.Request) string {
+	return req.URL.Host
+}
+
+// LimitPerRoute keeps a bucket per method, host and path.
+func LimitPerRoute(req *native.Request) string {
+	return req.Method + " " + req.URL.Host + req.URL.EscapedPath()
+}
+
+// WithFailFast returns a *RateLimitError straight away instead of waiting for
+// a token.
+func WithFailFast() LimiterOption {
+	return func(l *RateLimiter) {
+		l.failFast = true
+	}
+}
+
+// WithAdaptiveLimits holds requests back when a response says the quota is
+// used up, through Retry-After on a 429 or 503, or X-RateLimit-Remaining: 0
+// with X-RateLimit-Reset.
+func WithAdaptiveLimits() LimiterOption {
+	return func(l *RateLimiter) {
+		l.adaptive = true
+	}
+}
+
+// WithLimiterClock replaces time.Now, so tests can move time forward.
+func WithLimiterClock(now func() time.Time) LimiterOption {
+	return func(l *RateLimiter) {
+		l.now = now
+	}
+}
+
+// wait takes a token for req, waiting for one unless the limiter fails fast or
+// the context would expire first.
+func (l *RateLimiter) wait(req *native.Request) error {
+	key := l.key(req)
+
+	delay, err := l.reserve(req.Context(), key)
+	if err != nil || delay <= 0 {
+		return err
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		l.cancel(key)
+
+		return req.Context().Err()
+	}
+}
+
+// reserve takes a token and returns how long to wait until it may be used.
+func (l *RateLimiter) reserve(ctx context.Context, key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b := l.bucket(key, now)
+
+	delay := b.blocked.Sub(now)
+	if b.tokens < 1 {
+		delay = max(delay, time.Duration((1-b.tokens)/l.rate*float64(time.Second)))
+	}
+
+	if delay > 0 {
+		if l.failFast {
+			return 0, &RateLimitError{Key: key, RetryAfter: delay}
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && deadline.Before(now.Add(delay)) {
+			return 0, &RateLimitError{Key: key, RetryAfter: delay}
+		}
+	}
+
+	b.tokens--
+
+	return delay, nil
+}
+
+// cancel gives back the token of a request that stopped waiting.
+func (l *RateLimiter) cancel(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[key]; ok {
+		b.tokens = math.Min(l.burst, b.tokens+1)
+	}
+}
+
+// observe adapts to the quota the response reports.
+func (l *RateLimiter) observe(req *native.Request, resp *native.Response) {
+	if !l.adaptive || resp == nil {
+		return
+	}
+
+	until, ok := quotaReset(resp, l.now())
+	if !ok {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(l.key(req), l.now())
+	if until.After(b.blocked) {
+		b.blocked = until
+	}
+}
+
+// bucket returns the bucket for key refilled up to now.
+func (l *RateLimiter) bucket(key string, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+
+		return b
+	}
+
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed.Seconds()*l.rate)
+		b.updated = now
+	}
+
+	return b
+}
+
+// quotaReset returns when the quota resets according to resp, if it is used
+// up.
+func quotaReset(resp *native.Response, now time.Time) (time.Time, bool) {
+	if resp.StatusCode == native.StatusTooManyRequests || resp.StatusCode == native.StatusServiceUnavailable {
+		if until, ok := parseRetryAfter(resp.Header.Get(headerRetryAfter), now); ok {
+			return until, true
+		}
+	}
+
+	if resp.Header.Get(headerRateLimitRemaining) != "0" {
+		return time.Time{}, false
+	}
+
+	reset, err := strconv.ParseFloat(resp.Header.Get(headerRateLimitReset), 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	if reset > resetEpochThreshold {
+		return time.Unix(int64(reset), 0), true
+	}
+
+	return now.Add(time.Duration(reset * float64(time.Second))), true
+}
+
+// parseRetryAfter reads Retry-After as seconds or as an http date.
+func parseRetryAfter(value string, now time.Time) (time.Time, bool) {
+	if len(value) == 0 {
+		return time.Time{}, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+
+	if date, err := native.ParseTime(value); err == nil {
+		return date, true
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,189 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	native "net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/http"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	Convey("RateLimiter", t, func() {
+		ctx := context.Background()
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := func() time.Time { return now }
+
+		respond := func() *native.Response { return newResponse(native.StatusOK, nil) }
+
+		nativeMock := &http.NativeMock{}
+		nativeMock.On("Do", mock.Anything).Return(func(*native.Request) (*native.Response, error) {
+			return respond(), nil
+		})
+
+		newClient := func(limiters ...*http.RateLimiter) http.Client {
+			opts := make([]http.ClientOption, 0, len(limiters))
+			for _, limiter := range limiters {
+				opts = append(opts, http.WithRateLimiter(limiter))
+			}
+
+			return http.NewClient(nativeMock, encoder.NewFactory(), opts...)
+		}
+		call := func(c http.Client, url string) error {
+			return http.NewRequest(c).URL(url).DoAndUnmarshal(ctx, &struct{}{})
+		}
+
+		Convey("when failing fast", func() {
+			limiter := http.NewRateLimiter(2, 3, http.WithFailFast(), http.WithLimiterClock(clock))
+			c := newClient(limiter)
+
+			Convey("should allow a burst then refuse", func() {
+				for i := 0; i < 3; i++ {
+					So(call(c, "https://a.com"), ShouldBeNil)
+				}
+
+				err := call(c, "https://a.com")
+
+				var limitErr *http.RateLimitError
+
+				So(errors.As(err, &limitErr), ShouldBeTrue)
+				So(errors.Is(err, http.ErrRateLimited), ShouldBeTrue)
+				So(limitErr.RetryAfter, ShouldEqual, 500*time.Millisecond)
+				So(err, ShouldBeError, "rate limited, retry after 500ms")
+			})
+			Convey("should refill at the rate", func() {
+				for i := 0; i < 3; i++ {
+					So(call(c, "https://a.com"), ShouldBeNil)
+				}
+
+				now = now.Add(time.Second)
+
+				So(call(c, "https://a.com"), ShouldBeNil)
+				So(call(c, "https://a.com"), ShouldBeNil)
+				So(errors.Is(call(c, "https://a.com"), http.ErrRateLimited), ShouldBeTrue)
+			})
+		})
+		Convey("should keep a bucket per host", func() {
+			limiter := http.NewRateLimiter(1, 1, http.WithFailFast(), http.WithLimiterClock(clock),
+				http.WithLimitKey(http.LimitPerHost))
+			c := newClient(limiter)
+
+			So(call(c, "https://a.com"), ShouldBeNil)
+			So(call(c, "https://b.com"), ShouldBeNil)
+			So(call(c, "https://a.com"), ShouldBeError, "rate limited for a.com, retry after 1s")
+		})
+		Convey("should keep a bucket per route", func() {
+			limiter := http.NewRateLimiter(1, 1, http.WithFailFast(), http.WithLimiterClock(clock),
+				http.WithLimitKey(http.LimitPerRoute))
+			c := newClient(limiter)
+
+			So(call(c, "https://a.com/users"), ShouldBeNil)
+			So(call(c, "https://a.com/orders"), ShouldBeNil)
+			So(call(c, "https://a.com/users"), ShouldBeError, "rate limited for GET a.com/users, retry after 1s")
+		})
+		Convey("should apply every limiter of the client", func() {
+			perHost := http.NewRateLimiter(10, 10, http.WithFailFast(), http.WithLimiterClock(clock),
+				http.WithLimitKey(http.LimitPerHost))
+			global := http.NewRateLimiter(1, 2, http.WithFailFast(), http.WithLimiterClock(clock))
+			c := newClient(perHost, global)
+
+			So(call(c, "https://a.com"), ShouldBeNil)
+			So(call(c, "https://b.com"), ShouldBeNil)
+			So(errors.Is(call(c, "https://c.com"), http.ErrRateLimited), ShouldBeTrue)
+		})
+		Convey("when waiting", func() {
+			limiter := http.NewRateLimiter(20, 1)
+			c := newClient(limiter)
+
+			Convey("should wait for a token", func() {
+				start := time.Now()
+
+				So(call(c, "https://a.com"), ShouldBeNil)
+				So(call(c, "https://a.com"), ShouldBeNil)
+				So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 40*time.Millisecond)
+			})
+			Convey("should fail straight away when the context would expire first", func() {
+				So(call(c, "https://a.com"), ShouldBeNil)
+
+				ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+				defer cancel()
+
+				err := http.NewRequest(c).URL("https://a.com").DoAndUnmarshal(ctx, &struct{}{})
+
+				So(errors.Is(err, http.ErrRateLimited), ShouldBeTrue)
+			})
+			Convey("should stop waiting when the context is canceled", func() {
+				So(call(c, "https://a.com"), ShouldBeNil)
+
+				ctx, cancel := context.WithCancel(ctx)
+				time.AfterFunc(10*time.Millisecond, cancel)
+
+				err := http.NewRequest(c).URL("https://a.com").DoAndUnmarshal(ctx, &struct{}{})
+
+				So(errors.Is(err, context.Canceled), ShouldBeTrue)
+			})
+		})
+		Convey("when adapting to the server", func() {
+			limiter := http.NewRateLimiter(100, 100, http.WithFailFast(), http.WithLimiterClock(clock),
+				http.WithAdaptiveLimits())
+			c := newClient(limiter)
+
+			Convey("should hold back after Retry-After", func() {
+				respond = func() *native.Response {
+					resp := newResponse(native.StatusTooManyRequests, nil)
+					resp.Header = native.Header{"Retry-After": {"30"}}
+
+					return resp
+				}
+
+				So(call(c, "https://a.com"), ShouldBeError, "429: : bad requestBroker")
+				So(call(c, "https://a.com"), ShouldBeError, "rate limited, retry after 30s")
+
+				now = now.Add(30 * time.Second)
+				respond = func() *native.Response { return newResponse(native.StatusOK, nil) }
+
+				So(call(c, "https://a.com"), ShouldBeNil)
+			})
+			Convey("should hold back when the quota is used up", func() {
+				respond = func() *native.Response {
+					resp := newResponse(native.StatusOK, nil)
+					resp.Header = native.Header{
+						"X-Ratelimit-Remaining": {"0"},
+						"X-Ratelimit-Reset":     {strconv.FormatInt(now.Add(time.Minute).Unix(), 10)},
+					}
+
+					return resp
+				}
+
+				So(call(c, "https://a.com"), ShouldBeNil)
+				So(call(c, "https://a.com"), ShouldBeError, "rate limited, retry after 1m0s")
+			})
+			Convey("should accept the reset as seconds", func() {
+				respond = func() *native.Response {
+					resp := newResponse(native.StatusOK, nil)
+					resp.Header = native.Header{
+						"X-Ratelimit-Remaining": {"0"},
+						"X-Ratelimit-Reset":     {"5"},
+					}
+
+					return resp
+				}
+
+				So(call(c, "https://a.com"), ShouldBeNil)
+				So(call(c, "https://a.com"), ShouldBeError, "rate limited, retry after 5s")
+			})
+		})
+		Convey("should panic when rate is not positive", func() {
+			So(func() { http.NewRateLimiter(0, 1) }, ShouldPanicWith, "rate and burst must be positive")
+		})
+	})
+}
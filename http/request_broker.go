@@ -7,6 +7,10 @@ import (
 	native "net/http"
 	"net/url"
 	"strings"
+	"time"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/header"
 
 	"github.com/pkg/errors"
 )
@@ -15,92 +19,341 @@ import (
 //go:generate mockery --name=RequestBroker --structname=RequestBrokerMock --filename=request_broker_mock.go --inpackage
 type RequestBroker interface {
 	DoAndUnmarshal(ctx context.Context, v interface{}) error
-	Do(ctx context.Context) (io.Reader, error)
+	Do(ctx context.Context) (io.ReadCloser, error)
+	DoFunc(ctx context.Context, fn func(body io.Reader) error) error
+	DoResponse(ctx context.Context, v interface{}) (*Response, error)
+	Stream(ctx context.Context) (Iterator, error)
+	Paginate(ctx context.Context, v interface{}, strategy Pagination, opts ...PaginateOption) PageIterator
 
 	Post() RequestBroker
 	Get() RequestBroker
 	Put() RequestBroker
+	Patch() RequestBroker
 	Delete() RequestBroker
+	Head() RequestBroker
+	Options() RequestBroker
+	Method(method string) RequestBroker
 
 	URL(url string, v ...any) RequestBroker
+	Path(template string, params map[string]interface{}) RequestBroker
+	PathStruct(v interface{}) RequestBroker
 	Query(key string, value string) RequestBroker
+	AddQuery(key string, value string) RequestBroker
+	QueryStruct(v interface{}) RequestBroker
 	Header(key string, value string) RequestBroker
+	AddHeader(key string, value string) RequestBroker
 	Body(body string) RequestBroker
+	BodyReader(body io.Reader) RequestBroker
+	JSON(v interface{}) RequestBroker
+	XML(v interface{}) RequestBroker
+	Encode(v interface{}, mime string) RequestBroker
+	Form(key string, value string) RequestBroker
+	File(field string, filename string, reader io.Reader) RequestBroker
+	Compress(encoding string) RequestBroker
+	Timeout(d time.Duration) RequestBroker
+	Auth(auth Authenticator) RequestBroker
+	Sign(signer Signer) RequestBroker
+	Hedge(hedger *Hedger) RequestBroker
 
 	CreateRequest(ctx context.Context) (*native.Request, error)
+	Clone() RequestBroker
 }
 
+// maxDrain caps how much of an unread body DoFunc discards before closing, a
+// larger remainder is cheaper to drop with the connection.
+const maxDrain = 256 << 10
+
+// RequestOption configures a RequestBroker created by NewRequest.
+type RequestOption func(r *requestBroker)
+
 type requestBroker struct {
-	err    error
+	errs   []error
 	client Client
 	url    string
 
+	pathTemplate string
+	pathParams   map[string]interface{}
+
 	method  MethodType
-	headers map[string]string
-	query   map[string]string
-	body    string
+	headers native.Header
+	query   url.Values
+
+	body            []byte
+	bodyReader      io.Reader
+	form            url.Values
+	files           []formFile
+	contentEncoding string
+
+	timeout     time.Duration
+	auth        Authenticator
+	signer      Signer
+	hedger      *Hedger
+	factory     encoder.Factory
+	copyOnWrite bool
 }
 
-func NewRequest(client Client) RequestBroker {
+// WithBodyEncoderFactory sets the factory Encode, JSON and XML take their
+// encoder from, e.g. one made with encoder.WithJSONOptions.
+func WithBodyEncoderFactory(factory encoder.Factory) RequestOption {
+	return func(r *requestBroker) {
+		r.factory = factory
+	}
+}
+
+// WithCopyOnWrite makes every builder method leave the broker untouched and
+// return a modified copy instead. A broker configured once (base url, auth
+// headers) can then be shared between goroutines, each deriving its own
+// request from it.
+func WithCopyOnWrite() RequestOption {
+	return func(r *requestBroker) {
+		r.copyOnWrite = true
+	}
+}
+
+func NewRequest(client Client, opts ...RequestOption) RequestBroker {
 	r := &requestBroker{
-		method:  MethodGet,
-		headers: map[string]string{},
-		query:   map[string]string{},
+		method:     MethodGet,
+		headers:    native.Header{},
+		query:      url.Values{},
+		pathParams: map[string]interface{}{},
+		factory:    encoder.NewFactory(),
 	}
 
 	if client == nil {
-		r.setErrStr("native client is nil")
+		r.addErr(errors.New("native client is nil"))
 	}
 
 	r.client = client
 
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Clone returns a deep copy of the broker that can be changed without
+// affecting the original. Readers passed to BodyReader and File are shared,
+// as they can't be copied, so only one of the copies should send them.
+func (r *requestBroker) Clone() RequestBroker {
+	return r.clone()
+}
+
+func (r *requestBroker) clone() *requestBroker {
+	c := *r
+	c.errs = append([]error(nil), r.errs...)
+	c.headers = r.headers.Clone()
+	c.query = cloneValues(r.query)
+	c.form = cloneValues(r.form)
+	c.files = append([]formFile(nil), r.files...)
+
+	if r.pathParams != nil {
+		c.pathParams = make(map[string]interface{}, len(r.pathParams))
+		for k, v := range r.pathParams {
+			c.pathParams[k] = v
+		}
+	}
+
+	return &c
+}
+
+// writable returns the broker a builder method may change, which is a copy
+// when the broker is copy on write.
+func (r *requestBroker) writable() *requestBroker {
+	if r.copyOnWrite {
+		return r.clone()
+	}
+
 	return r
 }
 
 func (r *requestBroker) Post() RequestBroker {
+	r = r.writable()
+
 	r.method = MethodPost
 
 	return r
 }
 
 func (r *requestBroker) Get() RequestBroker {
+	r = r.writable()
+
 	r.method = MethodGet
 
 	return r
 }
 
 func (r *requestBroker) Put() RequestBroker {
+	r = r.writable()
+
 	r.method = MethodPut
 
 	return r
 }
 
+func (r *requestBroker) Patch() RequestBroker {
+	r = r.writable()
+
+	r.method = MethodPatch
+
+	return r
+}
+
 func (r *requestBroker) Delete() RequestBroker {
+	r = r.writable()
+
 	r.method = MethodDelete
 
 	return r
 }
 
+func (r *requestBroker) Head() RequestBroker {
+	r = r.writable()
+
+	r.method = MethodHead
+
+	return r
+}
+
+func (r *requestBroker) Options() RequestBroker {
+	r = r.writable()
+
+	r.method = MethodOptions
+
+	return r
+}
+
+// Method sets any method, including ones net/http has no constant for. It must
+// be a valid http token.
+func (r *requestBroker) Method(method string) RequestBroker {
+	r = r.writable()
+
+	if !isToken(method) {
+		r.addErr(errors.Errorf("invalid method %q", method))
+
+		return r
+	}
+
+	r.method = MethodType(method)
+
+	return r
+}
+
 func (r *requestBroker) URL(s string, v ...any) RequestBroker {
+	r = r.writable()
+
 	r.url = fmt.Sprintf(s, v...)
 
+	if _, err := url.Parse(r.url); err != nil {
+		r.addErr(err)
+	}
+
 	return r
 }
 
+// Query sets the query parameter, replacing any values it already had.
 func (r *requestBroker) Query(pattern, value string) RequestBroker {
-	r.query[pattern] = value
+	r = r.writable()
+
+	r.query.Set(pattern, value)
+
+	return r
+}
+
+// AddQuery appends a value to the query parameter, e.g. ?tag=a&tag=b.
+func (r *requestBroker) AddQuery(pattern, value string) RequestBroker {
+	r = r.writable()
+
+	r.query.Add(pattern, value)
 
 	return r
 }
 
+// Header sets the header, replacing any values it already had.
 func (r *requestBroker) Header(header, value string) RequestBroker {
-	r.headers[header] = value
+	r = r.writable()
+
+	if r.validHeader(header, value) {
+		r.headers.Set(header, value)
+	}
 
 	return r
 }
 
-func (r *requestBroker) Body(bytes string) RequestBroker {
-	r.body = bytes
+// AddHeader appends a value to the header.
+func (r *requestBroker) AddHeader(header, value string) RequestBroker {
+	r = r.writable()
+
+	if r.validHeader(header, value) {
+		r.headers.Add(header, value)
+	}
+
+	return r
+}
+
+// Timeout limits how long the request may take, from connecting until the
+// response body is read, overriding the Client default. Once it expires the
+// request fails with a *TimeoutError naming the phase that was slow.
+func (r *requestBroker) Timeout(d time.Duration) RequestBroker {
+	r = r.writable()
+
+	if d <= 0 {
+		r.addErr(errors.Errorf("timeout must be positive, got %s", d))
+
+		return r
+	}
+
+	r.timeout = d
+
+	return r
+}
+
+// Auth authenticates the request with auth instead of the Client default. The
+// credentials are added when the request is sent, so they are not part of the
+// request returned by CreateRequest.
+func (r *requestBroker) Auth(auth Authenticator) RequestBroker {
+	r = r.writable()
+
+	if auth == nil {
+		r.addErr(errors.New("authenticator is nil"))
+
+		return r
+	}
+
+	r.auth = auth
+
+	return r
+}
+
+// Sign signs the request with signer instead of the Client default. Like Auth
+// it is applied when the request is sent, to the final url and headers.
+func (r *requestBroker) Sign(signer Signer) RequestBroker {
+	r = r.writable()
+
+	if signer == nil {
+		r.addErr(errors.New("signer is nil"))
+
+		return r
+	}
+
+	r.signer = signer
+
+	return r
+}
+
+// Hedge sends a second attempt of the request when the first is slow to be
+// answered, using whichever answers first, instead of the Client default. It
+// only applies to idempotent methods.
+func (r *requestBroker) Hedge(hedger *Hedger) RequestBroker {
+	r = r.writable()
+
+	if hedger == nil {
+		r.addErr(errors.New("hedger is nil"))
+
+		return r
+	}
+
+	r.hedger = hedger
 
 	return r
 }
@@ -114,7 +367,20 @@ func (r *requestBroker) DoAndUnmarshal(ctx context.Context, out interface{}) err
 	return r.client.DoAndUnmarshal(req, out)
 }
 
-func (r *requestBroker) Do(ctx context.Context) (io.Reader, error) {
+// DoResponse decodes the response body into out and also returns the status,
+// headers, duration and final url of the response.
+func (r *requestBroker) DoResponse(ctx context.Context, out interface{}) (*Response, error) {
+	req, err := r.CreateRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.client.DoResponse(req, out)
+}
+
+// Do sends the request and returns the response body, which the caller must
+// close. DoFunc does that for you.
+func (r *requestBroker) Do(ctx context.Context) (io.ReadCloser, error) {
 	req, err := r.CreateRequest(ctx)
 	if err != nil {
 		return nil, err
@@ -123,15 +389,101 @@ func (r *requestBroker) Do(ctx context.Context) (io.Reader, error) {
 	return r.client.Do(req)
 }
 
-func (r *requestBroker) setErrStr(s string) {
-	if r.err != nil {
-		r.err = errors.New(s)
+// DoFunc sends the request and passes the response body to fn. Whatever fn
+// leaves unread is drained, up to maxDrain bytes so the connection can be
+// reused, and the body is always closed.
+func (r *requestBroker) DoFunc(ctx context.Context, fn func(body io.Reader) error) error {
+	body, err := r.Do(ctx)
+	if err != nil {
+		return err
 	}
+
+	defer func() {
+		_, _ = io.CopyN(io.Discard, body, maxDrain)
+		_ = body.Close()
+	}()
+
+	return fn(body)
+}
+
+// Stream sends the request and returns an iterator over a newline delimited
+// json response, decoding records with the json encoder of the broker's
+// factory. The caller is responsible for closing the iterator.
+func (r *requestBroker) Stream(ctx context.Context) (Iterator, error) {
+	if r.factory == nil {
+		return nil, errors.New("encoder factory is nil")
+	}
+
+	req, err := r.CreateRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.Header.Get(header.Accept)) == 0 {
+		req.Header.Set(header.Accept, encoder.ApplicationNDJSON)
+	}
+
+	body, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLineIterator(body, r.factory.FromMime(encoder.ApplicationJSON)), nil
+}
+
+// BuildError is returned by CreateRequest when more than one builder step
+// failed, it holds the errors in the order they were recorded.
+type BuildError []error
+
+func (e BuildError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+func (e BuildError) Unwrap() []error {
+	return e
+}
+
+// addErr records an error from a builder step, it is reported by
+// CreateRequest so the chain itself never has to be checked.
+func (r *requestBroker) addErr(err error) {
+	r.errs = append(r.errs, err)
+}
+
+func (r *requestBroker) buildErr() error {
+	switch len(r.errs) {
+	case 0:
+		return nil
+	case 1:
+		return r.errs[0]
+	default:
+		return append(BuildError(nil), r.errs...)
+	}
+}
+
+func (r *requestBroker) validHeader(key, value string) bool {
+	if !isToken(key) {
+		r.addErr(errors.Errorf("invalid header name %q", key))
+
+		return false
+	}
+
+	if strings.ContainsAny(value, "\r\n\x00") {
+		r.addErr(errors.Errorf("invalid value for header %q", key))
+
+		return false
+	}
+
+	return true
 }
 
 func (r *requestBroker) CreateRequest(ctx context.Context) (*native.Request, error) {
-	if r.err != nil {
-		return nil, r.err
+	if err := r.buildErr(); err != nil {
+		return nil, err
 	}
 
 	url, err := url.Parse(r.url)
@@ -139,6 +491,15 @@ func (r *requestBroker) CreateRequest(ctx context.Context) (*native.Request, err
 		return nil, err
 	}
 
+	if len(r.pathTemplate) > 0 {
+		path, err := r.renderPath()
+		if err != nil {
+			return nil, err
+		}
+
+		url = url.JoinPath(path)
+	}
+
 	req := &native.Request{
 		Method:     string(r.method),
 		URL:        url,
@@ -150,25 +511,74 @@ func (r *requestBroker) CreateRequest(ctx context.Context) (*native.Request, err
 		Host:       url.Host,
 	}
 
-	if len(r.body) > 0 {
-		req.ContentLength = int64(len(r.body))
-		req.Body = io.NopCloser(strings.NewReader(r.body))
-		req.GetBody = func() (io.ReadCloser, error) {
-			return io.NopCloser(strings.NewReader(r.body)), nil
+	for k, values := range r.headers {
+		for _, v := range values {
+			req.Header.Add(k, v)
 		}
 	}
 
-	for k, v := range r.headers {
-		req.Header.Add(k, v)
+	if err := r.setBody(req); err != nil {
+		return nil, err
+	}
+
+	if req.Body != nil && req.Body != native.NoBody && len(r.contentEncoding) > 0 {
+		req.Header.Set(header.ContentEncoding, r.contentEncoding)
 	}
 
 	query := req.URL.Query()
 
-	for k, v := range r.query {
-		query.Set(k, v)
+	for k, values := range r.query {
+		query[k] = append([]string{}, values...)
 	}
 
 	req.URL.RawQuery = query.Encode()
 
+	if r.timeout > 0 {
+		ctx = withTimeout(ctx, r.timeout)
+	}
+
+	if r.auth != nil {
+		ctx = withAuth(ctx, r.auth)
+	}
+
+	if r.signer != nil {
+		ctx = withSigner(ctx, r.signer)
+	}
+
+	if r.hedger != nil {
+		ctx = withHedger(ctx, r.hedger)
+	}
+
 	return req.WithContext(ctx), nil
 }
+
+func cloneValues(values url.Values) url.Values {
+	if values == nil {
+		return nil
+	}
+
+	c := make(url.Values, len(values))
+	for k, v := range values {
+		c[k] = append([]string(nil), v...)
+	}
+
+	return c
+}
+
+// isToken reports whether s is a valid http token (RFC 9110 section 5.6.2).
+func isToken(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,509 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	native "net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kevinanthony/gorps/v2/compress"
+	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/header"
+
+	"github.com/pkg/errors"
+)
+
+const applicationForm = "application/x-www-form-urlencoded"
+
+type formFile struct {
+	field    string
+	filename string
+	reader   io.Reader
+}
+
+// bodySource opens a fresh copy of the request body every time it is called.
+type bodySource struct {
+	open   func() (io.ReadCloser, error)
+	length int64
+	replay bool
+	// lazy delays open until the body is first read, for bodies that start a
+	// goroutine which would leak if the request is never sent.
+	lazy bool
+}
+
+func (r *requestBroker) Body(body string) RequestBroker {
+	r = r.writable()
+
+	r.resetBody()
+	r.body = []byte(body)
+
+	return r
+}
+
+// BodyReader streams body as the request body. When body is an io.Seeker the
+// request gets a Content-Length and can be replayed on redirects and retries.
+// The reader is not closed, that is left to the caller.
+func (r *requestBroker) BodyReader(body io.Reader) RequestBroker {
+	r = r.writable()
+
+	r.resetBody()
+	r.bodyReader = body
+
+	return r
+}
+
+// JSON encodes v as json for the body and sets Content-Type.
+func (r *requestBroker) JSON(v interface{}) RequestBroker {
+	return r.Encode(v, encoder.ApplicationJSON)
+}
+
+// XML encodes v as xml for the body and sets Content-Type.
+func (r *requestBroker) XML(v interface{}) RequestBroker {
+	return r.Encode(v, encoder.ApplicationXML)
+}
+
+// Encode encodes v with the encoder registered for mime and uses it as the
+// body, Content-Type is set to mime. Media types with a structured syntax
+// suffix are encoded with the encoder of their suffix, e.g.
+// application/vnd.api+json as json and application/atom+xml as xml. Media
+// types the encoder factory has no encoder for are an error.
+func (r *requestBroker) Encode(v interface{}, mime string) RequestBroker {
+	r = r.writable()
+
+	enc, err := r.bodyEncoder(mime)
+	if err != nil {
+		r.addErr(err)
+
+		return r
+	}
+
+	bts, err := enc.Encode(v)
+	if err != nil {
+		r.addErr(errors.Wrapf(err, "encode %s body", mime))
+
+		return r
+	}
+
+	r.resetBody()
+	r.headers.Set(header.ContentType, mime)
+	r.body = bts
+
+	return r
+}
+
+// bodyEncoder returns the encoder for mimeType. Factories fall back to a
+// default encoder for media types they don't know, whose output must not be
+// sent labelled as mimeType.
+func (r *requestBroker) bodyEncoder(mimeType string) (encoder.Encoder, error) {
+	if r.factory == nil {
+		return nil, errors.New("encoder factory is nil")
+	}
+
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "content type %q", mimeType)
+	}
+
+	fallback := r.factory.FromMime("").GetMime()
+	known := func(mediaType string) (encoder.Encoder, bool) {
+		enc := r.factory.FromMime(mediaType)
+
+		return enc, enc.GetMime() != fallback || mediaType == fallback
+	}
+
+	if enc, ok := known(mediaType); ok {
+		return enc, nil
+	}
+
+	// structured syntax suffixes (RFC 6839) name the format of the body
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		if enc, ok := known("application/" + mediaType[i+1:]); ok {
+			return enc, nil
+		}
+	}
+
+	return nil, errors.Errorf("no encoder for %s", mediaType)
+}
+
+// Form adds a form field. Without files the body is sent url encoded, with
+// files it becomes a field of the multipart body. It replaces a body set
+// before, along with its Content-Type.
+func (r *requestBroker) Form(key, value string) RequestBroker {
+	r = r.writable()
+
+	if r.body != nil || r.bodyReader != nil {
+		r.headers.Del(header.ContentType)
+	}
+
+	r.body = nil
+	r.bodyReader = nil
+
+	if r.form == nil {
+		r.form = url.Values{}
+	}
+
+	r.form.Add(key, value)
+
+	return r
+}
+
+// File adds a file to a multipart/form-data body. The reader is streamed, so
+// large files are never held in memory, and the request can be replayed when
+// every file is an io.Seeker. The reader is not closed, that is left to the
+// caller.
+func (r *requestBroker) File(field, filename string, reader io.Reader) RequestBroker {
+	r = r.writable()
+
+	r.body = nil
+	r.bodyReader = nil
+	r.files = append(r.files, formFile{
+		field:    field,
+		filename: filename,
+		reader:   reader,
+	})
+
+	return r
+}
+
+// Compress compresses the body with encoding (see the compress package) and
+// sets Content-Encoding.
+func (r *requestBroker) Compress(encoding string) RequestBroker {
+	r = r.writable()
+
+	r.contentEncoding = encoding
+
+	return r
+}
+
+func (r *requestBroker) resetBody() {
+	r.body = nil
+	r.bodyReader = nil
+	r.form = nil
+	r.files = nil
+}
+
+func (r *requestBroker) setBody(req *native.Request) error {
+	var comp compress.Compressor
+
+	if len(r.contentEncoding) > 0 {
+		var ok bool
+
+		comp, ok = compress.FromEncoding(r.contentEncoding)
+		if !ok {
+			return errors.Errorf("unsupported content encoding: %s", r.contentEncoding)
+		}
+	}
+
+	var (
+		src bodySource
+		err error
+	)
+
+	switch {
+	case len(r.files) > 0:
+		src, err = r.multipartSource(req)
+	case len(r.form) > 0:
+		if len(req.Header.Get(header.ContentType)) == 0 {
+			req.Header.Set(header.ContentType, applicationForm)
+		}
+
+		src, err = bytesSource([]byte(r.form.Encode()), comp)
+		comp = nil
+	case r.bodyReader != nil:
+		src, err = readerSource(r.bodyReader)
+	case len(r.body) > 0:
+		src, err = bytesSource(r.body, comp)
+		comp = nil
+	default:
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return applyBody(req, src, comp)
+}
+
+// applyBody sets the request body from src, compressing it on the fly when comp
+// is set.
+func applyBody(req *native.Request, src bodySource, comp compress.Compressor) error {
+	open := src.open
+	if comp != nil {
+		src.length = -1
+		src.lazy = true
+		open = func() (io.ReadCloser, error) {
+			body, err := src.open()
+			if err != nil {
+				return nil, err
+			}
+
+			return compressPipe(body, comp), nil
+		}
+	}
+
+	if src.lazy {
+		pipe := open
+		open = func() (io.ReadCloser, error) {
+			return &lazyBody{open: pipe}, nil
+		}
+	}
+
+	if src.length == 0 {
+		req.Body = native.NoBody
+		req.GetBody = func() (io.ReadCloser, error) { return native.NoBody, nil }
+
+		return nil
+	}
+
+	body, err := open()
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = src.length
+	req.Body = body
+
+	if src.replay {
+		req.GetBody = open
+	}
+
+	return nil
+}
+
+// bytesSource compresses up front, so the compressed length is known.
+func bytesSource(body []byte, comp compress.Compressor) (bodySource, error) {
+	if comp != nil {
+		var buf bytes.Buffer
+
+		if err := compressTo(&buf, bytes.NewReader(body), comp); err != nil {
+			return bodySource{}, err
+		}
+
+		body = buf.Bytes()
+	}
+
+	return bodySource{
+		open: func() (io.ReadCloser, error) {
+			return bytesBody{Reader: bytes.NewReader(body)}, nil
+		},
+		length: int64(len(body)),
+		replay: true,
+	}, nil
+}
+
+// bytesBody is a body held in memory. Every copy GetBody returns reads on its
+// own, so unlike streamed bodies copies can be sent at the same time.
+type bytesBody struct {
+	*bytes.Reader
+}
+
+func (bytesBody) Close() error {
+	return nil
+}
+
+func readerSource(body io.Reader) (bodySource, error) {
+	seeker, ok := body.(io.Seeker)
+	if !ok {
+		return bodySource{
+			open: func() (io.ReadCloser, error) {
+				return io.NopCloser(body), nil
+			},
+			length: -1,
+		}, nil
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return bodySource{}, err
+	}
+
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return bodySource{}, err
+	}
+
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return bodySource{}, err
+	}
+
+	return bodySource{
+		open: func() (io.ReadCloser, error) {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, err
+			}
+
+			return io.NopCloser(body), nil
+		},
+		length: end - start,
+		replay: true,
+	}, nil
+}
+
+// multipartSource streams the form fields and files through a pipe. Files are
+// rewound to where they started on every open, which is only possible when all
+// of them are seekable.
+func (r *requestBroker) multipartSource(req *native.Request) (bodySource, error) {
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	form := r.form
+	files := r.files
+	starts := make([]int64, len(files))
+	replay := true
+
+	for i, file := range files {
+		seeker, ok := file.reader.(io.Seeker)
+		if !ok {
+			replay = false
+
+			continue
+		}
+
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return bodySource{}, err
+		}
+
+		starts[i] = start
+	}
+
+	req.Header.Set(header.ContentType, "multipart/form-data; boundary="+boundary)
+
+	return bodySource{
+		open: func() (io.ReadCloser, error) {
+			if replay {
+				for i, file := range files {
+					if _, err := file.reader.(io.Seeker).Seek(starts[i], io.SeekStart); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			pr, pw := io.Pipe()
+
+			go func() {
+				_ = pw.CloseWithError(writeMultipart(pw, boundary, form, files))
+			}()
+
+			return pr, nil
+		},
+		length: -1,
+		replay: replay,
+		lazy:   true,
+	}, nil
+}
+
+func writeMultipart(w io.Writer, boundary string, form url.Values, files []formFile) error {
+	mw := multipart.NewWriter(w)
+
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range form[key] {
+			if err := mw.WriteField(key, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, file := range files {
+		part, err := mw.CreateFormFile(file.field, file.filename)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(part, file.reader); err != nil {
+			return err
+		}
+	}
+
+	return mw.Close()
+}
+
+// lazyBody opens its body on the first Read. Closing it before that never
+// opens it.
+type lazyBody struct {
+	open func() (io.ReadCloser, error)
+
+	mu     sync.Mutex
+	body   io.ReadCloser
+	err    error
+	closed bool
+}
+
+func (b *lazyBody) Read(p []byte) (int, error) {
+	body, err := b.opened()
+	if err != nil {
+		return 0, err
+	}
+
+	return body.Read(p)
+}
+
+func (b *lazyBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+
+	if b.body == nil {
+		return nil
+	}
+
+	return b.body.Close()
+}
+
+func (b *lazyBody) opened() (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, io.ErrClosedPipe
+	}
+
+	if b.body == nil && b.err == nil {
+		b.body, b.err = b.open()
+	}
+
+	return b.body, b.err
+}
+
+// compressPipe compresses body on the fly so large uploads are never held in
+// memory.
+func compressPipe(body io.Reader, comp compress.Compressor) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		_ = pw.CloseWithError(compressTo(pw, body, comp))
+	}()
+
+	return pr
+}
+
+func compressTo(dst io.Writer, src io.Reader, comp compress.Compressor) error {
+	w, err := comp.NewWriter(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, src); err != nil {
+		_ = w.Close()
+
+		return err
+	}
+
+	return w.Close()
+}
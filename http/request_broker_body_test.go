@@ -0,0 +1,432 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	native "net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kevinanthony/gorps/v2/compress"
+	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/http"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRequestBroker_Body(t *testing.T) {
+	t.Parallel()
+
+	Convey("Body", t, func() {
+		ctx := context.Background()
+		broker := http.NewRequest(&http.ClientMock{}).Post().URL("https://test.com/upload")
+
+		Convey("should set body and content length", func() {
+			req, err := broker.Body("payload").CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.ContentLength, ShouldEqual, 7)
+			So(readAll(req.Body), ShouldEqual, "payload")
+			So(readAll(getBody(req)), ShouldEqual, "payload")
+		})
+		Convey("should not set body when empty", func() {
+			req, err := broker.CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.Body, ShouldBeNil)
+			So(req.ContentLength, ShouldEqual, 0)
+		})
+	})
+}
+
+func TestRequestBroker_JSON(t *testing.T) {
+	t.Parallel()
+
+	Convey("JSON", t, func() {
+		ctx := context.Background()
+		broker := http.NewRequest(&http.ClientMock{}).Post().URL("https://test.com/upload")
+
+		Convey("should encode body and set content type", func() {
+			req, err := broker.JSON(map[string]int{"id": 1}).CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.Header.Get("Content-Type"), ShouldEqual, "application/json")
+			So(req.ContentLength, ShouldEqual, 8)
+			So(readAll(req.Body), ShouldEqual, `{"id":1}`)
+		})
+		Convey("should return error when value cannot be encoded", func() {
+			req, err := broker.JSON(func() {}).CreateRequest(ctx)
+
+			So(req, ShouldBeNil)
+			So(err, ShouldBeError)
+			So(err.Error(), ShouldStartWith, "encode application/json body: ")
+		})
+	})
+}
+
+func TestRequestBroker_XML(t *testing.T) {
+	t.Parallel()
+
+	Convey("XML", t, func() {
+		type item struct {
+			ID int `xml:"id"`
+		}
+
+		req, err := http.NewRequest(&http.ClientMock{}).Post().URL("https://test.com/upload").
+			XML(item{ID: 1}).
+			CreateRequest(context.Background())
+		So(err, ShouldBeNil)
+
+		So(req.Header.Get("Content-Type"), ShouldEqual, "application/xml")
+		So(readAll(req.Body), ShouldEqual, "<item><id>1</id></item>")
+	})
+}
+
+func TestRequestBroker_Encode(t *testing.T) {
+	t.Parallel()
+
+	Convey("Encode", t, func() {
+		ctx := context.Background()
+		broker := http.NewRequest(&http.ClientMock{}).Post().URL("https://test.com/upload")
+
+		Convey("should use encoder for mime", func() {
+			req, err := broker.Encode(map[string]int{"id": 1}, "application/yaml").CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.Header.Get("Content-Type"), ShouldEqual, "application/yaml")
+			So(readAll(req.Body), ShouldEqual, "id: 1\n")
+		})
+		Convey("should let header override content type", func() {
+			req, err := broker.
+				Encode(map[string]int{"id": 1}, "application/json").
+				Header("Content-Type", "application/vnd.api+json").
+				CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.Header.Values("Content-Type"), ShouldResemble, []string{"application/vnd.api+json"})
+		})
+		Convey("should replace body reader", func() {
+			req, err := broker.BodyReader(strings.NewReader("reader")).JSON(1).CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(readAll(req.Body), ShouldEqual, "1")
+		})
+		Convey("should encode structured syntax suffixes like their suffix", func() {
+			req, err := broker.Encode(map[string]int{"id": 1}, "application/vnd.api+json; charset=utf-8").CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.Header.Get("Content-Type"), ShouldEqual, "application/vnd.api+json; charset=utf-8")
+			So(readAll(req.Body), ShouldEqual, `{"id":1}`)
+
+			req, err = broker.Encode("id", "application/atom+xml").CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(readAll(req.Body), ShouldEqual, "<string>id</string>")
+
+			req, err = broker.Encode(1, "application/vnd.foo+cbor").CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So([]byte(readAll(req.Body)), ShouldResemble, []byte{0x01})
+		})
+		Convey("should use aliases of registered types", func() {
+			req, err := broker.Encode("id", encoder.TextXML).CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.Header.Get("Content-Type"), ShouldEqual, encoder.TextXML)
+		})
+		Convey("should use the encoder factory of the broker", func() {
+			factory := encoder.NewFactory(encoder.WithJSONOptions(encoder.JSONIndent(2)))
+
+			req, err := http.NewRequest(&http.ClientMock{}, http.WithBodyEncoderFactory(factory)).Post().
+				URL("https://test.com/upload").JSON(map[string]int{"id": 1}).CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(readAll(req.Body), ShouldEqual, "{\n  \"id\": 1\n}")
+		})
+		Convey("should return error when", func() {
+			Convey("no encoder is registered for mime", func() {
+				_, err := broker.Encode([]string{"a"}, "text/csv").CreateRequest(ctx)
+
+				So(err, ShouldBeError, "no encoder for text/csv")
+			})
+			Convey("no encoder is registered for the suffix of mime", func() {
+				_, err := broker.Encode(1, "application/vnd.foo+bar").CreateRequest(ctx)
+
+				So(err, ShouldBeError, "no encoder for application/vnd.foo+bar")
+			})
+			Convey("mime is invalid", func() {
+				_, err := broker.Encode(1, "not a mime").CreateRequest(ctx)
+
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestRequestBroker_BodyReader(t *testing.T) {
+	t.Parallel()
+
+	Convey("BodyReader", t, func() {
+		ctx := context.Background()
+		broker := http.NewRequest(&http.ClientMock{}).Post().URL("https://test.com/upload")
+
+		Convey("should stream reader with unknown length", func() {
+			req, err := broker.BodyReader(io.MultiReader(strings.NewReader("stream"))).CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.ContentLength, ShouldEqual, -1)
+			So(req.GetBody, ShouldBeNil)
+			So(readAll(req.Body), ShouldEqual, "stream")
+		})
+		Convey("should use length and allow replay when reader is seekable", func() {
+			reader := strings.NewReader("skip seekable")
+			_, err := reader.Seek(5, io.SeekStart)
+			So(err, ShouldBeNil)
+
+			req, err := broker.BodyReader(reader).CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.ContentLength, ShouldEqual, 8)
+			So(readAll(req.Body), ShouldEqual, "seekable")
+			So(readAll(getBody(req)), ShouldEqual, "seekable")
+		})
+		Convey("should send no body when seekable reader is empty", func() {
+			req, err := broker.BodyReader(bytes.NewReader(nil)).CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.Body, ShouldEqual, native.NoBody)
+		})
+		Convey("should compress reader", func() {
+			req, err := broker.BodyReader(strings.NewReader("compressed stream")).Compress(compress.Gzip).CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.ContentLength, ShouldEqual, -1)
+			So(req.Header.Get("Content-Encoding"), ShouldEqual, compress.Gzip)
+
+			reader, err := compress.NewGzip().NewReader(req.Body)
+			So(err, ShouldBeNil)
+			So(readAll(reader), ShouldEqual, "compressed stream")
+
+			reader, err = compress.NewGzip().NewReader(getBody(req))
+			So(err, ShouldBeNil)
+			So(readAll(reader), ShouldEqual, "compressed stream")
+		})
+		Convey("should not start compressing until the body is read", func() {
+			source := &countingReader{Reader: strings.NewReader("compressed stream")}
+
+			req, err := broker.BodyReader(source).Compress(compress.Gzip).CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			time.Sleep(20 * time.Millisecond)
+			So(req.Body.Close(), ShouldBeNil)
+
+			So(source.reads.Load(), ShouldEqual, 0)
+		})
+		Convey("should return error when", func() {
+			Convey("reader cannot seek", func() {
+				req, err := broker.BodyReader(badSeeker{}).CreateRequest(ctx)
+
+				So(req, ShouldBeNil)
+				So(err, ShouldBeError, "cannot seek")
+			})
+		})
+	})
+}
+
+func TestRequestBroker_Form(t *testing.T) {
+	t.Parallel()
+
+	Convey("Form", t, func() {
+		ctx := context.Background()
+		broker := http.NewRequest(&http.ClientMock{}).Post().URL("https://test.com/login")
+
+		Convey("should url encode fields", func() {
+			req, err := broker.Body("replaced").Form("user", "kevin").Form("scope", "a").Form("scope", "b").CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.Header.Get("Content-Type"), ShouldEqual, "application/x-www-form-urlencoded")
+			So(req.ContentLength, ShouldEqual, 26)
+			So(readAll(req.Body), ShouldEqual, "scope=a&scope=b&user=kevin")
+			So(readAll(getBody(req)), ShouldEqual, "scope=a&scope=b&user=kevin")
+		})
+		Convey("should keep content type set by header", func() {
+			req, err := broker.Form("user", "kevin").Header("Content-Type", "application/x-www-form-urlencoded; charset=utf-8").CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.Header.Get("Content-Type"), ShouldEqual, "application/x-www-form-urlencoded; charset=utf-8")
+		})
+		Convey("should replace the content type of an encoded body", func() {
+			req, err := broker.JSON(map[string]int{"id": 1}).Form("user", "kevin").CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.Header.Values("Content-Type"), ShouldResemble, []string{"application/x-www-form-urlencoded"})
+			So(readAll(req.Body), ShouldEqual, "user=kevin")
+		})
+		Convey("should be replaced by body", func() {
+			req, err := broker.Form("user", "kevin").Body("body").CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.Header.Get("Content-Type"), ShouldBeEmpty)
+			So(readAll(req.Body), ShouldEqual, "body")
+		})
+	})
+}
+
+func TestRequestBroker_File(t *testing.T) {
+	t.Parallel()
+
+	Convey("File", t, func() {
+		ctx := context.Background()
+		broker := http.NewRequest(&http.ClientMock{}).Post().URL("https://test.com/upload")
+
+		Convey("should build multipart body with fields and files", func() {
+			req, err := broker.
+				Form("name", "kevin").
+				File("avatar", "me.png", strings.NewReader("png bytes")).
+				File("resume", "cv.txt", strings.NewReader("text bytes")).
+				CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.ContentLength, ShouldEqual, -1)
+			So(req.Header.Get("Content-Type"), ShouldStartWith, "multipart/form-data; boundary=")
+
+			for _, body := range []func() io.ReadCloser{
+				func() io.ReadCloser { return req.Body },
+				func() io.ReadCloser { return getBody(req) },
+			} {
+				req.Body = body()
+				So(req.ParseMultipartForm(1<<20), ShouldBeNil)
+
+				So(req.MultipartForm.Value["name"], ShouldResemble, []string{"kevin"})
+				So(req.MultipartForm.File["avatar"][0].Filename, ShouldEqual, "me.png")
+				So(readFile(req, "avatar"), ShouldEqual, "png bytes")
+				So(readFile(req, "resume"), ShouldEqual, "text bytes")
+
+				req.MultipartForm = nil
+			}
+		})
+		Convey("should not replay when a file is not seekable", func() {
+			req, err := broker.File("avatar", "me.png", io.MultiReader(strings.NewReader("png bytes"))).CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.GetBody, ShouldBeNil)
+			So(req.ParseMultipartForm(1<<20), ShouldBeNil)
+			So(readFile(req, "avatar"), ShouldEqual, "png bytes")
+		})
+		Convey("should not start writing the body until it is read", func() {
+			file := &countingReader{Reader: strings.NewReader("png bytes")}
+
+			req, err := broker.File("avatar", "me.png", file).CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			time.Sleep(20 * time.Millisecond)
+			So(req.Body.Close(), ShouldBeNil)
+
+			So(file.reads.Load(), ShouldEqual, 0)
+		})
+		Convey("should return error when file cannot seek", func() {
+			req, err := broker.File("avatar", "me.png", badSeeker{}).CreateRequest(ctx)
+
+			So(req, ShouldBeNil)
+			So(err, ShouldBeError, "cannot seek")
+		})
+		Convey("should be replaced by body", func() {
+			req, err := broker.File("avatar", "me.png", strings.NewReader("png bytes")).JSON(1).CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.Header.Get("Content-Type"), ShouldEqual, "application/json")
+			So(readAll(req.Body), ShouldEqual, "1")
+		})
+	})
+}
+
+func TestRequestBroker_Compress(t *testing.T) {
+	t.Parallel()
+
+	Convey("Compress", t, func() {
+		ctx := context.Background()
+		broker := http.NewRequest(&http.ClientMock{}).Post().URL("https://test.com/upload").Body("payload payload payload")
+
+		Convey("should compress body and set content encoding", func() {
+			req, err := broker.Compress(compress.Gzip).CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.Header.Get("Content-Encoding"), ShouldEqual, compress.Gzip)
+
+			for _, body := range []io.ReadCloser{req.Body, getBody(req)} {
+				reader, err := compress.NewGzip().NewReader(body)
+				So(err, ShouldBeNil)
+
+				actual, err := io.ReadAll(reader)
+				So(err, ShouldBeNil)
+				So(string(actual), ShouldEqual, "payload payload payload")
+			}
+		})
+		Convey("should not set content encoding when body is empty", func() {
+			req, err := http.NewRequest(&http.ClientMock{}).URL("https://test.com").Compress(compress.Zstd).CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.Header.Get("Content-Encoding"), ShouldBeEmpty)
+			So(req.Body, ShouldBeNil)
+		})
+		Convey("should return error when encoding is not supported", func() {
+			req, err := broker.Compress("compress").CreateRequest(ctx)
+
+			So(req, ShouldBeNil)
+			So(err, ShouldBeError, "unsupported content encoding: compress")
+		})
+	})
+}
+
+func getBody(req *native.Request) io.ReadCloser {
+	body, err := req.GetBody()
+	if err != nil {
+		panic(err)
+	}
+
+	return body
+}
+
+func readAll(r io.Reader) string {
+	bts, err := io.ReadAll(r)
+	if err != nil {
+		panic(err)
+	}
+
+	return string(bts)
+}
+
+// countingReader counts how often it is read.
+type countingReader struct {
+	io.Reader
+	reads atomic.Int32
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.reads.Add(1)
+
+	return r.Reader.Read(p)
+}
+
+func readFile(req *native.Request, field string) string {
+	file, err := req.MultipartForm.File[field][0].Open()
+	if err != nil {
+		panic(err)
+	}
+
+	defer file.Close()
+
+	return readAll(file)
+}
+
+type badSeeker struct {
+	io.Reader
+}
+
+func (badSeeker) Seek(int64, int) (int64, error) {
+	return 0, errors.New("cannot seek")
+}
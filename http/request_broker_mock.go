@@ -9,6 +9,8 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	nethttp "net/http"
+
+	time "time"
 )
 
 // RequestBrokerMock is an autogenerated mock type for the RequestBroker type
@@ -16,6 +18,54 @@ type RequestBrokerMock struct {
 	mock.Mock
 }
 
+// AddHeader provides a mock function with given fields: key, value
+func (_m *RequestBrokerMock) AddHeader(key string, value string) RequestBroker {
+	ret := _m.Called(key, value)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(string, string) RequestBroker); ok {
+		r0 = rf(key, value)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
+// AddQuery provides a mock function with given fields: key, value
+func (_m *RequestBrokerMock) AddQuery(key string, value string) RequestBroker {
+	ret := _m.Called(key, value)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(string, string) RequestBroker); ok {
+		r0 = rf(key, value)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
+// Auth provides a mock function with given fields: auth
+func (_m *RequestBrokerMock) Auth(auth Authenticator) RequestBroker {
+	ret := _m.Called(auth)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(Authenticator) RequestBroker); ok {
+		r0 = rf(auth)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
 // Body provides a mock function with given fields: body
 func (_m *RequestBrokerMock) Body(body string) RequestBroker {
 	ret := _m.Called(body)
@@ -32,6 +82,54 @@ func (_m *RequestBrokerMock) Body(body string) RequestBroker {
 	return r0
 }
 
+// BodyReader provides a mock function with given fields: body
+func (_m *RequestBrokerMock) BodyReader(body io.Reader) RequestBroker {
+	ret := _m.Called(body)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(io.Reader) RequestBroker); ok {
+		r0 = rf(body)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
+// Clone provides a mock function with given fields:
+func (_m *RequestBrokerMock) Clone() RequestBroker {
+	ret := _m.Called()
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func() RequestBroker); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
+// Compress provides a mock function with given fields: encoding
+func (_m *RequestBrokerMock) Compress(encoding string) RequestBroker {
+	ret := _m.Called(encoding)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(string) RequestBroker); ok {
+		r0 = rf(encoding)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
 // CreateRequest provides a mock function with given fields: ctx
 func (_m *RequestBrokerMock) CreateRequest(ctx context.Context) (*nethttp.Request, error) {
 	ret := _m.Called(ctx)
@@ -75,19 +173,19 @@ func (_m *RequestBrokerMock) Delete() RequestBroker {
 }
 
 // Do provides a mock function with given fields: ctx
-func (_m *RequestBrokerMock) Do(ctx context.Context) (io.Reader, error) {
+func (_m *RequestBrokerMock) Do(ctx context.Context) (io.ReadCloser, error) {
 	ret := _m.Called(ctx)
 
-	var r0 io.Reader
+	var r0 io.ReadCloser
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) (io.Reader, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context) (io.ReadCloser, error)); ok {
 		return rf(ctx)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) io.Reader); ok {
+	if rf, ok := ret.Get(0).(func(context.Context) io.ReadCloser); ok {
 		r0 = rf(ctx)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(io.Reader)
+			r0 = ret.Get(0).(io.ReadCloser)
 		}
 	}
 
@@ -114,6 +212,94 @@ func (_m *RequestBrokerMock) DoAndUnmarshal(ctx context.Context, v interface{})
 	return r0
 }
 
+// DoFunc provides a mock function with given fields: ctx, fn
+func (_m *RequestBrokerMock) DoFunc(ctx context.Context, fn func(io.Reader) error) error {
+	ret := _m.Called(ctx, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(io.Reader) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DoResponse provides a mock function with given fields: ctx, v
+func (_m *RequestBrokerMock) DoResponse(ctx context.Context, v interface{}) (*Response, error) {
+	ret := _m.Called(ctx, v)
+
+	var r0 *Response
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (*Response, error)); ok {
+		return rf(ctx, v)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) *Response); ok {
+		r0 = rf(ctx, v)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Response)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, v)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Encode provides a mock function with given fields: v, mime
+func (_m *RequestBrokerMock) Encode(v interface{}, mime string) RequestBroker {
+	ret := _m.Called(v, mime)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(interface{}, string) RequestBroker); ok {
+		r0 = rf(v, mime)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
+// File provides a mock function with given fields: field, filename, reader
+func (_m *RequestBrokerMock) File(field string, filename string, reader io.Reader) RequestBroker {
+	ret := _m.Called(field, filename, reader)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(string, string, io.Reader) RequestBroker); ok {
+		r0 = rf(field, filename, reader)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
+// Form provides a mock function with given fields: key, value
+func (_m *RequestBrokerMock) Form(key string, value string) RequestBroker {
+	ret := _m.Called(key, value)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(string, string) RequestBroker); ok {
+		r0 = rf(key, value)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields:
 func (_m *RequestBrokerMock) Get() RequestBroker {
 	ret := _m.Called()
@@ -130,6 +316,22 @@ func (_m *RequestBrokerMock) Get() RequestBroker {
 	return r0
 }
 
+// Head provides a mock function with given fields:
+func (_m *RequestBrokerMock) Head() RequestBroker {
+	ret := _m.Called()
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func() RequestBroker); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
 // Header provides a mock function with given fields: key, value
 func (_m *RequestBrokerMock) Header(key string, value string) RequestBroker {
 	ret := _m.Called(key, value)
@@ -146,6 +348,141 @@ func (_m *RequestBrokerMock) Header(key string, value string) RequestBroker {
 	return r0
 }
 
+// Hedge provides a mock function with given fields: hedger
+func (_m *RequestBrokerMock) Hedge(hedger *Hedger) RequestBroker {
+	ret := _m.Called(hedger)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(*Hedger) RequestBroker); ok {
+		r0 = rf(hedger)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
+// JSON provides a mock function with given fields: v
+func (_m *RequestBrokerMock) JSON(v interface{}) RequestBroker {
+	ret := _m.Called(v)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(interface{}) RequestBroker); ok {
+		r0 = rf(v)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
+// Method provides a mock function with given fields: method
+func (_m *RequestBrokerMock) Method(method string) RequestBroker {
+	ret := _m.Called(method)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(string) RequestBroker); ok {
+		r0 = rf(method)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
+// Options provides a mock function with given fields:
+func (_m *RequestBrokerMock) Options() RequestBroker {
+	ret := _m.Called()
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func() RequestBroker); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
+// Paginate provides a mock function with given fields: ctx, v, strategy, opts
+func (_m *RequestBrokerMock) Paginate(ctx context.Context, v interface{}, strategy Pagination, opts ...PaginateOption) PageIterator {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, v, strategy)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 PageIterator
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, Pagination, ...PaginateOption) PageIterator); ok {
+		r0 = rf(ctx, v, strategy, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(PageIterator)
+		}
+	}
+
+	return r0
+}
+
+// Patch provides a mock function with given fields:
+func (_m *RequestBrokerMock) Patch() RequestBroker {
+	ret := _m.Called()
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func() RequestBroker); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
+// Path provides a mock function with given fields: template, params
+func (_m *RequestBrokerMock) Path(template string, params map[string]interface{}) RequestBroker {
+	ret := _m.Called(template, params)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(string, map[string]interface{}) RequestBroker); ok {
+		r0 = rf(template, params)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
+// PathStruct provides a mock function with given fields: v
+func (_m *RequestBrokerMock) PathStruct(v interface{}) RequestBroker {
+	ret := _m.Called(v)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(interface{}) RequestBroker); ok {
+		r0 = rf(v)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
 // Post provides a mock function with given fields:
 func (_m *RequestBrokerMock) Post() RequestBroker {
 	ret := _m.Called()
@@ -194,6 +531,80 @@ func (_m *RequestBrokerMock) Query(key string, value string) RequestBroker {
 	return r0
 }
 
+// QueryStruct provides a mock function with given fields: v
+func (_m *RequestBrokerMock) QueryStruct(v interface{}) RequestBroker {
+	ret := _m.Called(v)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(interface{}) RequestBroker); ok {
+		r0 = rf(v)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
+// Sign provides a mock function with given fields: signer
+func (_m *RequestBrokerMock) Sign(signer Signer) RequestBroker {
+	ret := _m.Called(signer)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(Signer) RequestBroker); ok {
+		r0 = rf(signer)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
+// Stream provides a mock function with given fields: ctx
+func (_m *RequestBrokerMock) Stream(ctx context.Context) (Iterator, error) {
+	ret := _m.Called(ctx)
+
+	var r0 Iterator
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (Iterator, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) Iterator); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(Iterator)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Timeout provides a mock function with given fields: d
+func (_m *RequestBrokerMock) Timeout(d time.Duration) RequestBroker {
+	ret := _m.Called(d)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(time.Duration) RequestBroker); ok {
+		r0 = rf(d)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
 // URL provides a mock function with given fields: url, v
 func (_m *RequestBrokerMock) URL(url string, v ...interface{}) RequestBroker {
 	var _ca []interface{}
@@ -213,6 +624,22 @@ func (_m *RequestBrokerMock) URL(url string, v ...interface{}) RequestBroker {
 	return r0
 }
 
+// XML provides a mock function with given fields: v
+func (_m *RequestBrokerMock) XML(v interface{}) RequestBroker {
+	ret := _m.Called(v)
+
+	var r0 RequestBroker
+	if rf, ok := ret.Get(0).(func(interface{}) RequestBroker); ok {
+		r0 = rf(v)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(RequestBroker)
+		}
+	}
+
+	return r0
+}
+
 // NewRequestBrokerMock creates a new instance of RequestBrokerMock. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewRequestBrokerMock(t interface {
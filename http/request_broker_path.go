@@ -0,0 +1,124 @@
+package http
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const pathTag = "path"
+
+// Path sets a templated path like /users/{id}/orders/{orderID}. Every {name} is
+// replaced by the matching param, percent-escaped so values can't add path
+// segments, queries or fragments. The path is appended to the URL set with
+// URL, or to the client's base URL when URL is not set.
+func (r *requestBroker) Path(template string, params map[string]interface{}) RequestBroker {
+	r = r.writable()
+
+	r.pathTemplate = template
+
+	for k, v := range params {
+		r.pathParams[k] = v
+	}
+
+	return r
+}
+
+// PathStruct adds a path param for every field of v with a path tag, the same
+// tag RequestHandler uses to fill structs.
+func (r *requestBroker) PathStruct(v interface{}) RequestBroker {
+	r = r.writable()
+
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		r.addErr(errors.Errorf("path struct: expected struct, got %T", v))
+
+		return r
+	}
+
+	typeOf := value.Type()
+
+	for i := 0; i < value.NumField(); i++ {
+		field := typeOf.Field(i)
+
+		name, found := field.Tag.Lookup(pathTag)
+		if !found || name == "-" || !field.IsExported() {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		if fieldValue.Kind() == reflect.Ptr {
+			if fieldValue.IsNil() {
+				continue
+			}
+
+			fieldValue = fieldValue.Elem()
+		}
+
+		s, err := queryValue(fieldValue)
+		if err != nil {
+			r.addErr(errors.Wrapf(err, "path struct: field %s", field.Name))
+
+			return r
+		}
+
+		r.pathParams[name] = s
+	}
+
+	return r
+}
+
+func (r *requestBroker) renderPath() (string, error) {
+	var (
+		sb       strings.Builder
+		template = r.pathTemplate
+	)
+
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			sb.WriteString(template)
+
+			return sb.String(), nil
+		}
+
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return "", errors.Errorf("path template %q: unclosed {", r.pathTemplate)
+		}
+
+		name := template[start+1 : start+end]
+
+		param, found := r.pathParams[name]
+		if !found {
+			return "", errors.Errorf("path template %q: missing param %q", r.pathTemplate, name)
+		}
+
+		segment := fmt.Sprint(param)
+		if len(segment) == 0 {
+			return "", errors.Errorf("path template %q: empty param %q", r.pathTemplate, name)
+		}
+
+		sb.WriteString(template[:start])
+		sb.WriteString(escapeSegment(segment))
+
+		template = template[start+end+1:]
+	}
+}
+
+// escapeSegment percent-escapes s as a single path segment. Dot segments are
+// escaped too, otherwise cleaning the path would let them walk up the tree.
+func escapeSegment(s string) string {
+	if s == "." || s == ".." {
+		return strings.Repeat("%2E", len(s))
+	}
+
+	return url.PathEscape(s)
+}
@@ -0,0 +1,126 @@
+package http_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kevinanthony/gorps/v2/http"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRequestBroker_Path(t *testing.T) {
+	t.Parallel()
+
+	Convey("Path", t, func() {
+		ctx := context.Background()
+		broker := http.NewRequest(&http.ClientMock{}).URL("https://test.com/api/")
+
+		Convey("should fill template and append it to url", func() {
+			req, err := broker.
+				Path("/users/{id}/orders/{orderID}", map[string]interface{}{"id": 42, "orderID": "a-1"}).
+				CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.URL.String(), ShouldEqual, "https://test.com/api/users/42/orders/a-1")
+		})
+		Convey("should escape params", func() {
+			req, err := broker.
+				Path("/files/{name}", map[string]interface{}{"name": "../a b/c?d#e"}).
+				CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.URL.String(), ShouldEqual, "https://test.com/api/files/..%2Fa%20b%2Fc%3Fd%23e")
+			So(req.URL.RawQuery, ShouldBeEmpty)
+		})
+		Convey("should escape dot segments", func() {
+			req, err := broker.
+				Path("/users/{id}/orders", map[string]interface{}{"id": ".."}).
+				CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.URL.String(), ShouldEqual, "https://test.com/api/users/%2E%2E/orders")
+		})
+		Convey("should keep query parameters", func() {
+			req, err := broker.
+				Path("/users/{id}", map[string]interface{}{"id": 1}).
+				Query("expand", "orders").
+				CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.URL.String(), ShouldEqual, "https://test.com/api/users/1?expand=orders")
+		})
+		Convey("should be relative without url", func() {
+			req, err := http.NewRequest(&http.ClientMock{}).
+				Path("/users/{id}", map[string]interface{}{"id": 1}).
+				CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.URL.Host, ShouldBeEmpty)
+			So(req.URL.Path, ShouldEqual, "users/1")
+		})
+		Convey("should return error when", func() {
+			Convey("param is missing", func() {
+				req, err := broker.Path("/users/{id}", nil).CreateRequest(ctx)
+
+				So(req, ShouldBeNil)
+				So(err, ShouldBeError, `path template "/users/{id}": missing param "id"`)
+			})
+			Convey("param is empty", func() {
+				req, err := broker.Path("/users/{id}", map[string]interface{}{"id": ""}).CreateRequest(ctx)
+
+				So(req, ShouldBeNil)
+				So(err, ShouldBeError, `path template "/users/{id}": empty param "id"`)
+			})
+			Convey("brace is not closed", func() {
+				req, err := broker.Path("/users/{id", map[string]interface{}{"id": 1}).CreateRequest(ctx)
+
+				So(req, ShouldBeNil)
+				So(err, ShouldBeError, `path template "/users/{id": unclosed {`)
+			})
+		})
+	})
+}
+
+func TestRequestBroker_PathStruct(t *testing.T) {
+	t.Parallel()
+
+	Convey("PathStruct", t, func() {
+		ctx := context.Background()
+		broker := http.NewRequest(&http.ClientMock{}).URL("https://test.com")
+
+		Convey("should fill template from path tags", func() {
+			type params struct {
+				ID      int     `path:"id"`
+				OrderID *string `path:"orderID"`
+				Ignored string
+			}
+
+			orderID := "a/1"
+
+			req, err := broker.
+				Path("/users/{id}/orders/{orderID}", nil).
+				PathStruct(&params{ID: 42, OrderID: &orderID}).
+				CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.URL.String(), ShouldEqual, "https://test.com/users/42/orders/a%2F1")
+		})
+		Convey("should return error when", func() {
+			Convey("value is not a struct", func() {
+				req, err := broker.PathStruct(1).CreateRequest(ctx)
+
+				So(req, ShouldBeNil)
+				So(err, ShouldBeError, "path struct: expected struct, got int")
+			})
+			Convey("field kind is not supported", func() {
+				req, err := broker.PathStruct(struct {
+					IDs []int `path:"ids"`
+				}{}).CreateRequest(ctx)
+
+				So(req, ShouldBeNil)
+				So(err, ShouldBeError, "path struct: field IDs: unsupported kind: slice")
+			})
+		})
+	})
+}
@@ -0,0 +1,124 @@
+package http
+
+import (
+	"encoding"
+	"reflect"
+	"strconv"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	queryTag = "query"
+	base10   = 10
+)
+
+// QueryStruct adds a query parameter for every field of v with a query tag,
+// the same tag RequestHandler uses to fill structs. Slices add one value per
+// element, nil pointers are skipped, encoding.TextMarshaler (e.g. time.Time)
+// is used when implemented and structs and maps are sent as json.
+func (r *requestBroker) QueryStruct(v interface{}) RequestBroker {
+	r = r.writable()
+
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		r.addErr(errors.Errorf("query struct: expected struct, got %T", v))
+
+		return r
+	}
+
+	typeOf := value.Type()
+
+	for i := 0; i < value.NumField(); i++ {
+		field := typeOf.Field(i)
+
+		name, found := field.Tag.Lookup(queryTag)
+		if !found || name == "-" || !field.IsExported() {
+			continue
+		}
+
+		values, err := queryValues(value.Field(i))
+		if err != nil {
+			r.addErr(errors.Wrapf(err, "query struct: field %s", field.Name))
+
+			return r
+		}
+
+		for _, s := range values {
+			r.query.Add(name, s)
+		}
+	}
+
+	return r
+}
+
+func queryValues(value reflect.Value) ([]string, error) {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, nil
+		}
+
+		value = value.Elem()
+	}
+
+	if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+		return []string{string(value.Bytes())}, nil
+	}
+
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		values := make([]string, 0, value.Len())
+
+		for i := 0; i < value.Len(); i++ {
+			items, err := queryValues(value.Index(i))
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, items...)
+		}
+
+		return values, nil
+	}
+
+	s, err := queryValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{s}, nil
+}
+
+//nolint:cyclop // this is just a big switch, nothing complex
+func queryValue(value reflect.Value) (string, error) {
+	if marshaler, ok := value.Interface().(encoding.TextMarshaler); ok {
+		bts, err := marshaler.MarshalText()
+
+		return string(bts), err
+	}
+
+	//nolint: exhaustive
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), base10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), base10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, value.Type().Bits()), nil
+	case reflect.Struct, reflect.Map:
+		bts, err := encoder.NewJSON().Encode(value.Interface())
+
+		return string(bts), err
+	default:
+		return "", errors.Errorf("unsupported kind: %s", value.Kind())
+	}
+}
@@ -0,0 +1,102 @@
+package http_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kevinanthony/gorps/v2/http"
+	"github.com/kevinanthony/gorps/v2/internal/testx"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRequestBroker_QueryStruct(t *testing.T) {
+	t.Parallel()
+
+	Convey("QueryStruct", t, func() {
+		ctx := context.Background()
+		broker := http.NewRequest(&http.ClientMock{}).URL("https://test.com/search")
+
+		Convey("should add query tagged fields", func() {
+			page := 2
+			type search struct {
+				Tags    []string  `query:"tag"`
+				IDs     [2]uint   `query:"id"`
+				Page    *int      `query:"page"`
+				Limit   *int      `query:"limit"`
+				Since   time.Time `query:"since"`
+				Ratio   float32   `query:"ratio"`
+				Raw     []byte    `query:"raw"`
+				Skipped string    `query:"-"`
+				NoTag   string
+				private string `query:"private"`
+			}
+
+			req, err := broker.QueryStruct(&search{
+				Tags:    []string{"a", "b"},
+				IDs:     [2]uint{1, 2},
+				Page:    &page,
+				Since:   time.Date(1989, 11, 9, 18, 0o1, 0o0, 0o0, time.UTC),
+				Ratio:   0.5,
+				Raw:     []byte("raw"),
+				Skipped: "skipped",
+				NoTag:   "no tag",
+				private: "private",
+			}).CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			So(req.URL.RawQuery, ShouldEqual, "id=1&id=2&page=2&ratio=0.5&raw=raw&since=1989-11-09T18%3A01%3A00Z&tag=a&tag=b")
+		})
+		Convey("should round trip through RequestHandler", func() {
+			type query struct {
+				String string           `query:"string"`
+				Int    int              `query:"int"`
+				UInt   uint             `query:"uint"`
+				Float  float64          `query:"float"`
+				Bool   bool             `query:"bool"`
+				JSON   testx.JSONGambit `query:"json"`
+				Since  time.Time        `query:"since"`
+				Until  *time.Time       `query:"until"`
+			}
+
+			test := testx.GetTestStruct()
+			until := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+			expected := query{
+				String: test.QueryString,
+				Int:    test.QueryInt,
+				UInt:   test.QueryUInt,
+				Float:  test.QueryFloat,
+				Bool:   test.QueryBool,
+				JSON:   test.QueryJSON,
+				Since:  time.Date(1989, 11, 9, 18, 1, 0, 0, time.UTC),
+				Until:  &until,
+			}
+
+			req, err := broker.QueryStruct(expected).CreateRequest(ctx)
+			So(err, ShouldBeNil)
+
+			actual := query{}
+			err = http.NewRequestHandlerHelper().Fill(req, &actual)
+
+			So(err, ShouldBeNil)
+			So(actual, ShouldResemble, expected)
+		})
+		Convey("should return error when", func() {
+			Convey("value is not a struct", func() {
+				req, err := broker.QueryStruct("nope").CreateRequest(ctx)
+
+				So(req, ShouldBeNil)
+				So(err, ShouldBeError, "query struct: expected struct, got string")
+			})
+			Convey("field kind is not supported", func() {
+				req, err := broker.QueryStruct(struct {
+					C chan int `query:"c"`
+				}{}).CreateRequest(ctx)
+
+				So(req, ShouldBeNil)
+				So(err, ShouldBeError, "query struct: field C: unsupported kind: chan")
+			})
+		})
+	})
+}
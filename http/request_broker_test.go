@@ -1,27 +1,523 @@
 package http_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	native "net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/http"
+
 	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestNewRequest(t *testing.T) {
 	t.Parallel()
 
 	Convey("NewRequest", t, func() {
-		//	Convey("should return type jsonEncoding", func() {
-		//		actual := http.NewRequest(http.MethodPost, "google.com", "search")
-		//
-		//		So(reflect.TypeOf(actual).String(), ShouldEqual, "*http.requestBroker")
-		//	})
+		ctx := context.Background()
+
+		Convey("should return a broker that creates requests", func() {
+			req, err := http.NewRequest(&http.ClientMock{}).URL("https://test.com").CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.URL.String(), ShouldEqual, "https://test.com")
+		})
+		Convey("when client is nil", func() {
+			broker := http.NewRequest(nil).URL("https://test.com")
+
+			Convey("should return error from CreateRequest", func() {
+				req, err := broker.CreateRequest(ctx)
+
+				So(req, ShouldBeNil)
+				So(err, ShouldBeError, "native client is nil")
+			})
+			Convey("should return error instead of sending", func() {
+				So(func() { _, _ = broker.Do(ctx) }, ShouldNotPanic)
+
+				_, err := broker.Do(ctx)
+				So(err, ShouldBeError, "native client is nil")
+
+				So(broker.DoAndUnmarshal(ctx, &map[string]interface{}{}), ShouldBeError, "native client is nil")
+
+				_, err = broker.Stream(ctx)
+				So(err, ShouldBeError, "native client is nil")
+			})
+		})
+	})
+}
+
+func TestRequestBroker_Do(t *testing.T) {
+	t.Parallel()
+
+	Convey("Do", t, func() {
+		ctx := context.Background()
+		clientMock := &http.ClientMock{}
+		broker := http.NewRequest(clientMock).URL("https://test.com")
+
+		Convey("should send the created request", func() {
+			body := io.NopCloser(strings.NewReader("ok"))
+			clientMock.On("Do", mock.MatchedBy(func(req *native.Request) bool {
+				return req.URL.String() == "https://test.com"
+			})).Return(body, nil).Once()
+
+			actual, err := broker.Do(ctx)
+
+			So(err, ShouldBeNil)
+			So(actual, ShouldEqual, body)
+			mock.AssertExpectationsForObjects(t, clientMock)
+		})
+		Convey("should pass the created request to DoResponse", func() {
+			expected := &http.Response{StatusCode: native.StatusOK}
+			out := &map[string]interface{}{}
+			clientMock.On("DoResponse", mock.AnythingOfType("*http.Request"), out).Return(expected, nil).Once()
+
+			actual, err := broker.DoResponse(ctx, out)
+
+			So(err, ShouldBeNil)
+			So(actual, ShouldEqual, expected)
+			mock.AssertExpectationsForObjects(t, clientMock)
+		})
+		Convey("DoFunc", func() {
+			body := &trackedBody{Reader: strings.NewReader("hello world")}
+			clientMock.On("Do", mock.Anything).Return(body, nil).Once()
+
+			Convey("should pass the body to fn then drain and close it", func() {
+				var read string
+
+				err := broker.DoFunc(ctx, func(r io.Reader) error {
+					bts := make([]byte, 5)
+					_, err := io.ReadFull(r, bts)
+					read = string(bts)
+
+					return err
+				})
+
+				So(err, ShouldBeNil)
+				So(read, ShouldEqual, "hello")
+				So(body.Len(), ShouldEqual, 0)
+				So(body.closed, ShouldBeTrue)
+			})
+			Convey("should close the body when fn fails", func() {
+				expected := errors.New("decode failed")
+
+				err := broker.DoFunc(ctx, func(io.Reader) error { return expected })
+
+				So(err, ShouldEqual, expected)
+				So(body.closed, ShouldBeTrue)
+			})
+		})
+		Convey("DoFunc should not call fn when sending failed", func() {
+			clientMock.On("Do", mock.Anything).Return(nil, errors.New("upstream is down")).Once()
+
+			called := false
+			err := broker.DoFunc(ctx, func(io.Reader) error {
+				called = true
+
+				return nil
+			})
+
+			So(err, ShouldBeError, "upstream is down")
+			So(called, ShouldBeFalse)
+		})
+		Convey("should not send when building failed", func() {
+			_, err := broker.Header("Bad Header", "value").Do(ctx)
+
+			So(err, ShouldBeError, `invalid header name "Bad Header"`)
+			clientMock.AssertNotCalled(t, "Do", mock.Anything)
+		})
+	})
+}
+
+func TestRequestBroker_Errors(t *testing.T) {
+	t.Parallel()
+
+	Convey("builder errors", t, func() {
+		ctx := context.Background()
+
+		Convey("should return error for invalid url", func() {
+			_, err := http.NewRequest(&http.ClientMock{}).URL("://test.com").CreateRequest(ctx)
+
+			So(err, ShouldBeError, `parse "://test.com": missing protocol scheme`)
+		})
+		Convey("should return error for invalid header value", func() {
+			_, err := http.NewRequest(&http.ClientMock{}).AddHeader("X-Test", "a\r\nb").CreateRequest(ctx)
+
+			So(err, ShouldBeError, `invalid value for header "X-Test"`)
+		})
+		Convey("should report every error in order", func() {
+			encodeErr := errors.New("encode failed")
+
+			_, err := http.NewRequest(nil).
+				URL("://test.com").
+				Header("Bad Header", "value").
+				Encode(errorMarshaler{err: encodeErr}, encoder.ApplicationJSON).
+				CreateRequest(ctx)
+
+			var buildErr http.BuildError
+
+			So(errors.As(err, &buildErr), ShouldBeTrue)
+			So(buildErr, ShouldHaveLength, 4)
+			So(errors.Is(err, encodeErr), ShouldBeTrue)
+			So(err.Error(), ShouldStartWith, `native client is nil; parse "://test.com": missing protocol scheme; `+
+				`invalid header name "Bad Header"; encode application/json body: `)
+		})
+	})
+}
+
+func TestRequestBroker_Method(t *testing.T) {
+	t.Parallel()
+
+	Convey("Method", t, func() {
+		ctx := context.Background()
+		broker := http.NewRequest(&http.ClientMock{}).URL("https://test.com")
+
+		Convey("should default to GET", func() {
+			req, err := broker.CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.Method, ShouldEqual, http.MethodGet)
+		})
+		Convey("should set method with", func() {
+			for expected, set := range map[string]func() http.RequestBroker{
+				http.MethodPost:    broker.Post,
+				http.MethodGet:     broker.Get,
+				http.MethodPut:     broker.Put,
+				http.MethodPatch:   broker.Patch,
+				http.MethodDelete:  broker.Delete,
+				http.MethodHead:    broker.Head,
+				http.MethodOptions: broker.Options,
+			} {
+				req, err := set().CreateRequest(ctx)
+
+				So(err, ShouldBeNil)
+				So(req.Method, ShouldEqual, expected)
+			}
+		})
+		Convey("should set custom method", func() {
+			req, err := broker.Method("PROPFIND").CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.Method, ShouldEqual, "PROPFIND")
+		})
+		Convey("should return error when custom method is not a token", func() {
+			for _, method := range []string{"", "GET /", "GÉT", "GET\n"} {
+				req, err := http.NewRequest(&http.ClientMock{}).Method(method).CreateRequest(ctx)
+
+				So(req, ShouldBeNil)
+				So(err, ShouldBeError, fmt.Sprintf("invalid method %q", method))
+			}
+		})
+	})
+}
+
+func TestRequestBroker_Query(t *testing.T) {
+	t.Parallel()
+
+	Convey("Query", t, func() {
+		ctx := context.Background()
+		broker := http.NewRequest(&http.ClientMock{}).URL("https://test.com/search?tag=x&page=1")
+
+		Convey("should replace values with Query", func() {
+			req, err := broker.Query("tag", "a").Query("tag", "b").CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.URL.Query()["tag"], ShouldResemble, []string{"b"})
+			So(req.URL.Query().Get("page"), ShouldEqual, "1")
+		})
+		Convey("should keep every value with AddQuery", func() {
+			req, err := broker.AddQuery("tag", "a").AddQuery("tag", "b").CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.URL.RawQuery, ShouldEqual, "page=1&tag=a&tag=b")
+		})
+	})
+}
+
+func TestRequestBroker_Header(t *testing.T) {
+	t.Parallel()
+
+	Convey("Header", t, func() {
+		ctx := context.Background()
+		broker := http.NewRequest(&http.ClientMock{}).URL("https://test.com")
+
+		Convey("should replace values with Header", func() {
+			req, err := broker.Header("x-trace", "a").Header("X-Trace", "b").CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.Header.Values("X-Trace"), ShouldResemble, []string{"b"})
+		})
+		Convey("should keep every value with AddHeader", func() {
+			req, err := broker.AddHeader("Accept", "application/json").AddHeader("accept", "application/xml").CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.Header.Values("Accept"), ShouldResemble, []string{"application/json", "application/xml"})
+		})
+	})
+}
+
+func TestRequestBroker_Stream(t *testing.T) {
+	t.Parallel()
+
+	Convey("Stream", t, func() {
+		ctx := context.Background()
+		clientMock := &http.ClientMock{}
+		bodyMock := &http.BodyMock{}
+
+		broker := http.NewRequest(clientMock).Get().URL("https://test.com/export")
+
+		type record struct {
+			ID int `json:"id"`
+		}
+
+		Convey("should decode one record at a time", func() {
+			body := io.NopCloser(strings.NewReader("{\"id\":1}\n\n{\"id\":2}\n{\"id\":3}"))
+			clientMock.On("Do", mock.MatchedBy(func(req *native.Request) bool {
+				return req.Header.Get("Accept") == encoder.ApplicationNDJSON
+			})).Return(body, nil).Once()
+
+			iter, err := broker.Stream(ctx)
+			So(err, ShouldBeNil)
+
+			var actual []record
+
+			for iter.Next() {
+				var rec record
+
+				So(iter.Decode(&rec), ShouldBeNil)
+
+				actual = append(actual, rec)
+			}
+
+			So(iter.Err(), ShouldBeNil)
+			So(iter.Close(), ShouldBeNil)
+			So(actual, ShouldResemble, []record{{ID: 1}, {ID: 2}, {ID: 3}})
+			mock.AssertExpectationsForObjects(t, clientMock)
+		})
+		Convey("should keep accept header when already set", func() {
+			clientMock.On("Do", mock.MatchedBy(func(req *native.Request) bool {
+				return req.Header.Get("Accept") == "application/json-seq"
+			})).Return(io.NopCloser(strings.NewReader("")), nil).Once()
+
+			iter, err := broker.Header("Accept", "application/json-seq").Stream(ctx)
+
+			So(err, ShouldBeNil)
+			So(iter.Next(), ShouldBeFalse)
+			mock.AssertExpectationsForObjects(t, clientMock)
+		})
+		Convey("should not set the accept header on the broker", func() {
+			clientMock.On("Do", mock.Anything).Return(io.NopCloser(strings.NewReader("")), nil).Once()
+
+			_, err := broker.Stream(ctx)
+			So(err, ShouldBeNil)
+
+			req, err := broker.CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.Header.Get("Accept"), ShouldBeEmpty)
+		})
+		Convey("should decode with the broker's encoder factory", func() {
+			clientMock.On("Do", mock.Anything).Return(io.NopCloser(strings.NewReader(`{"id":1,"name":"a"}`)), nil).Once()
+
+			factory := encoder.NewFactory(encoder.WithJSONOptions(encoder.JSONDisallowUnknownFields()))
+			broker := http.NewRequest(clientMock, http.WithBodyEncoderFactory(factory)).URL("https://test.com/export")
+
+			iter, err := broker.Stream(ctx)
+			So(err, ShouldBeNil)
+
+			var rec record
+
+			So(iter.Next(), ShouldBeTrue)
+			So(iter.Decode(&rec), ShouldNotBeNil)
+		})
+		Convey("should close body", func() {
+			bodyMock.On("Close").Return(nil).Once()
+			clientMock.On("Do", mock.Anything).Return(bodyMock, nil).Once()
+
+			iter, err := broker.Stream(ctx)
+
+			So(err, ShouldBeNil)
+			So(iter.Close(), ShouldBeNil)
+			mock.AssertExpectationsForObjects(t, clientMock, bodyMock)
+		})
+		Convey("should return error when", func() {
+			Convey("encoder factory is nil", func() {
+				iter, err := http.NewRequest(clientMock, http.WithBodyEncoderFactory(nil)).Stream(ctx)
+
+				So(iter, ShouldBeNil)
+				So(err, ShouldBeError, "encoder factory is nil")
+			})
+			Convey("client returns an error", func() {
+				clientMock.On("Do", mock.Anything).Return(nil, errors.New("upstream is down")).Once()
+
+				iter, err := broker.Stream(ctx)
+
+				So(iter, ShouldBeNil)
+				So(err, ShouldBeError, "upstream is down")
+			})
+			Convey("body read fails", func() {
+				bodyMock.On("Read", mock.Anything).Return(0, errors.New("everybody body mock")).Once()
+				clientMock.On("Do", mock.Anything).Return(bodyMock, nil).Once()
+
+				iter, err := broker.Stream(ctx)
+				So(err, ShouldBeNil)
+
+				So(iter.Next(), ShouldBeFalse)
+				So(iter.Err(), ShouldBeError, "everybody body mock")
+			})
+			Convey("record is not valid json", func() {
+				clientMock.On("Do", mock.Anything).Return(io.NopCloser(strings.NewReader("{\n")), nil).Once()
+
+				iter, err := broker.Stream(ctx)
+				So(err, ShouldBeNil)
+
+				var rec record
+
+				So(iter.Next(), ShouldBeTrue)
+				So(iter.Decode(&rec), ShouldBeError)
+			})
+		})
 	})
 }
 
-func TestRequest_Go(t *testing.T) {
+func TestRequestBroker_Clone(t *testing.T) {
 	t.Parallel()
 
-	Convey("Go", t, func() {
-		// TODO no unit test yet
+	Convey("Clone", t, func() {
+		ctx := context.Background()
+		template := http.NewRequest(&http.ClientMock{}).
+			URL("https://test.com").
+			Path("/users/{id}", map[string]interface{}{"id": 1}).
+			Header("Authorization", "Bearer token").
+			Query("page", "1").
+			Form("name", "bob")
+
+		Convey("should copy every setting", func() {
+			req, err := template.Clone().CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.URL.String(), ShouldEqual, "https://test.com/users/1?page=1")
+			So(req.Header.Get("Authorization"), ShouldEqual, "Bearer token")
+			So(readAll(req.Body), ShouldEqual, "name=bob")
+		})
+		Convey("should not change the original when the clone changes", func() {
+			template.Clone().
+				Path("/users/{id}", map[string]interface{}{"id": 2}).
+				Header("Authorization", "Bearer other").
+				AddQuery("page", "2").
+				Form("name", "alice")
+
+			req, err := template.CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.URL.String(), ShouldEqual, "https://test.com/users/1?page=1")
+			So(req.Header.Get("Authorization"), ShouldEqual, "Bearer token")
+			So(readAll(req.Body), ShouldEqual, "name=bob")
+		})
+		Convey("should be safe to clone concurrently", func() {
+			urls := deriveConcurrently(func(i int) http.RequestBroker {
+				return template.Clone().Query("page", strconv.Itoa(i))
+			})
+
+			for i, u := range urls {
+				So(u, ShouldEqual, fmt.Sprintf("https://test.com/users/1?page=%d", i))
+			}
+		})
 	})
 }
+
+func TestRequestBroker_CopyOnWrite(t *testing.T) {
+	t.Parallel()
+
+	Convey("WithCopyOnWrite", t, func() {
+		ctx := context.Background()
+		template := http.NewRequest(&http.ClientMock{}, http.WithCopyOnWrite()).
+			URL("https://test.com").
+			Header("Authorization", "Bearer token")
+
+		Convey("should leave the broker unchanged", func() {
+			template.Post().Path("/users", nil).Query("page", "2").JSON(map[string]int{"a": 1})
+
+			req, err := template.CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+			So(req.Method, ShouldEqual, http.MethodGet)
+			So(req.URL.String(), ShouldEqual, "https://test.com")
+			So(req.Header.Get("Content-Type"), ShouldBeEmpty)
+			So(req.Body, ShouldBeNil)
+		})
+		Convey("should keep errors on the derived broker", func() {
+			_, err := template.Method("BAD METHOD").CreateRequest(ctx)
+
+			So(err, ShouldBeError, `invalid method "BAD METHOD"`)
+
+			_, err = template.CreateRequest(ctx)
+
+			So(err, ShouldBeNil)
+		})
+		Convey("should be safe to derive requests concurrently", func() {
+			urls := deriveConcurrently(func(i int) http.RequestBroker {
+				return template.Path("/users/{id}", map[string]interface{}{"id": i}).Header("X-Id", strconv.Itoa(i))
+			})
+
+			for i, u := range urls {
+				So(u, ShouldEqual, fmt.Sprintf("https://test.com/users/%d", i))
+			}
+		})
+	})
+}
+
+// deriveConcurrently builds a request per goroutine and returns their urls.
+func deriveConcurrently(derive func(i int) http.RequestBroker) []string {
+	const count = 50
+
+	urls := make([]string, count)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			req, err := derive(i).CreateRequest(context.Background())
+			if err != nil {
+				urls[i] = err.Error()
+
+				return
+			}
+
+			urls[i] = req.URL.String()
+		}(i)
+	}
+
+	wg.Wait()
+
+	return urls
+}
+
+type errorMarshaler struct {
+	err error
+}
+
+func (e errorMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, e.err
+}
+
+type trackedBody struct {
+	*strings.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+
+	return nil
+}
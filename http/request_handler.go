@@ -1,10 +1,12 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"net/http"
 	"strconv"
 
+	"github.com/kevinanthony/gorps/v2/compress"
 	"github.com/kevinanthony/gorps/v2/encoder"
 	"github.com/kevinanthony/gorps/v2/header"
 	"github.com/kevinanthony/gorps/v2/http/internal"
@@ -17,24 +19,98 @@ type RequestHandler interface {
 	MarshalAndVerify(r *http.Request, dst interface{}) error
 }
 
+// RequestHandlerOption configures a RequestHandler.
+type RequestHandlerOption func(rh *requestHandler)
+
 type requestHandler struct {
-	helper internal.RequestHandlerHelper
+	helper  internal.RequestHandlerHelper
+	factory encoder.Factory
+
+	compress        bool
+	compressMinSize int
+
+	verifier Verifier
 }
 
-func NewRequestHandler(helper internal.RequestHandlerHelper) RequestHandler {
+func NewRequestHandler(helper internal.RequestHandlerHelper, opts ...RequestHandlerOption) RequestHandler {
 	if helper == nil {
 		panic("request handler helper is required")
 	}
 
-	return &requestHandler{
-		helper: helper,
+	rh := &requestHandler{
+		helper:  helper,
+		factory: encoder.NewFactory(),
+	}
+
+	for _, opt := range opts {
+		opt(rh)
+	}
+
+	if rh.factory == nil {
+		panic("encoder factory is required")
+	}
+
+	return rh
+}
+
+// WithEncoderFactory sets the factory used to pick the response encoder.
+func WithEncoderFactory(factory encoder.Factory) RequestHandlerOption {
+	return func(rh *requestHandler) {
+		rh.factory = factory
+	}
+}
+
+// WithCompression compresses responses of at least minSize bytes with the best
+// encoding the client accepts (gzip, deflate, br or zstd). Streamed responses
+// are never compressed.
+func WithCompression(minSize int) RequestHandlerOption {
+	return func(rh *requestHandler) {
+		rh.compress = true
+		rh.compressMinSize = minSize
+	}
+}
+
+// WithVerifier rejects requests that verifier refuses with 401 before the
+// handler func runs, e.g. a NewHMACVerifier or NewSigV4Verifier.
+func WithVerifier(verifier Verifier) RequestHandlerOption {
+	return func(rh *requestHandler) {
+		rh.verifier = verifier
+	}
+}
+
+// HelperOption configures the helper returned by NewRequestHandlerHelper.
+type HelperOption func(cfg *helperConfig)
+
+type helperConfig struct {
+	factoryOpts []encoder.FactoryOption
+	setterOpts  []internal.SetterOption
+}
+
+// WithFactoryOptions configures the encoders used to decode request bodies.
+func WithFactoryOptions(opts ...encoder.FactoryOption) HelperOption {
+	return func(cfg *helperConfig) {
+		cfg.factoryOpts = append(cfg.factoryOpts, opts...)
 	}
 }
 
-func NewRequestHandlerHelper() internal.RequestHandlerHelper {
+// WithMaxDecompressedSize limits how large a compressed request body may be
+// once decompressed, it defaults to internal.DefaultMaxDecompressedSize.
+func WithMaxDecompressedSize(size int64) HelperOption {
+	return func(cfg *helperConfig) {
+		cfg.setterOpts = append(cfg.setterOpts, internal.WithMaxDecompressedSize(size))
+	}
+}
+
+func NewRequestHandlerHelper(opts ...HelperOption) internal.RequestHandlerHelper {
+	cfg := helperConfig{}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	return internal.NewRequestHandlerHelper(
 		internal.NewRequestHandlerSetter(
-			encoder.NewFactory()))
+			encoder.NewFactory(cfg.factoryOpts...), cfg.setterOpts...))
 }
 
 func (rh requestHandler) MarshalAndVerify(r *http.Request, dst interface{}) error {
@@ -43,20 +119,34 @@ func (rh requestHandler) MarshalAndVerify(r *http.Request, dst interface{}) erro
 
 func (rh requestHandler) Handle(f RequestHandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if rh.verifier != nil {
+			if err := rh.verifier.Verify(r); err != nil {
+				rh.write(w, r, http.StatusUnauthorized, err.Error())
+
+				return
+			}
+		}
+
 		resp, err := f(r.Context(), r)
 		if err != nil {
-			write(w, r, http.StatusBadRequest, err.Error())
+			rh.write(w, r, http.StatusBadRequest, err.Error())
 
 			return
 		}
 
 		// TODO create interface to get StatusCode
-		write(w, r, http.StatusOK, resp)
+		rh.write(w, r, http.StatusOK, resp)
 	}
 }
 
-func write(w http.ResponseWriter, r *http.Request, statusCode int, src interface{}) {
-	enc := encoder.NewFactory().CreateFromRequest(r)
+func (rh requestHandler) write(w http.ResponseWriter, r *http.Request, statusCode int, src interface{}) {
+	enc := rh.factory.CreateFromRequest(r)
+
+	if stream, ok := enc.(encoder.StreamEncoder); ok {
+		writeStream(w, stream, statusCode, src)
+
+		return
+	}
 
 	bts, err := enc.Encode(src)
 	if err != nil {
@@ -68,14 +158,74 @@ func write(w http.ResponseWriter, r *http.Request, statusCode int, src interface
 		return
 	}
 
-	w.Header().Set("Access-Control-Allow-Credentials", "true")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
-	w.Header().Add(header.ContentType, enc.GetMime())
+	writeHeaders(w, enc.GetMime())
+
+	bts = rh.compressBody(w, r, bts)
+
 	w.Header().Add(header.ContentLength, strconv.Itoa(len(bts)))
 
 	w.WriteHeader(statusCode)
 
 	_, _ = w.Write(bts)
 }
+
+// writeStream sends the headers straight away and lets the encoder write to
+// the response as values are produced. Once the first byte is out there is no
+// way to change the status code, so encoding errors just end the stream. If the
+// client goes away the encoder keeps draining a channel source until it is
+// closed, so producers should still stop on r.Context().Done().
+func writeStream(w http.ResponseWriter, enc encoder.StreamEncoder, statusCode int, src interface{}) {
+	writeHeaders(w, enc.GetMime())
+
+	w.WriteHeader(statusCode)
+
+	_ = enc.EncodeTo(w, src)
+}
+
+// compressBody returns bts compressed with the negotiated encoding and sets
+// Content-Encoding, if anything goes wrong the uncompressed bytes are sent.
+func (rh requestHandler) compressBody(w http.ResponseWriter, r *http.Request, bts []byte) []byte {
+	if !rh.compress {
+		return bts
+	}
+
+	w.Header().Add(header.Vary, header.AcceptEncoding)
+
+	if len(bts) < rh.compressMinSize {
+		return bts
+	}
+
+	encoding := compress.Negotiate(r.Header.Get(header.AcceptEncoding))
+
+	comp, ok := compress.FromEncoding(encoding)
+	if !ok {
+		return bts
+	}
+
+	var buf bytes.Buffer
+
+	cw, err := comp.NewWriter(&buf)
+	if err != nil {
+		return bts
+	}
+
+	if _, err := cw.Write(bts); err != nil {
+		return bts
+	}
+
+	if err := cw.Close(); err != nil {
+		return bts
+	}
+
+	w.Header().Set(header.ContentEncoding, encoding)
+
+	return buf.Bytes()
+}
+
+func writeHeaders(w http.ResponseWriter, mime string) {
+	w.Header().Set("Access-Control-Allow-Credentials", "true")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+	w.Header().Add(header.ContentType, mime)
+}
@@ -0,0 +1,196 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	native "net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kevinanthony/gorps/v2/compress"
+	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/http"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewRequestHandler(t *testing.T) {
+	t.Parallel()
+
+	Convey("NewRequestHandler", t, func() {
+		helper := http.NewRequestHandlerHelper()
+
+		Convey("should return new request handler", func() {
+			f := func() { http.NewRequestHandler(helper) }
+
+			So(f, ShouldNotPanic)
+		})
+		Convey("should panic when", func() {
+			Convey("helper is nil", func() {
+				f := func() { http.NewRequestHandler(nil) }
+
+				So(f, ShouldPanicWith, "request handler helper is required")
+			})
+			Convey("encoder factory is nil", func() {
+				f := func() { http.NewRequestHandler(helper, http.WithEncoderFactory(nil)) }
+
+				So(f, ShouldPanicWith, "encoder factory is required")
+			})
+		})
+	})
+}
+
+func TestRequestHandler_Handle(t *testing.T) {
+	t.Parallel()
+
+	Convey("Handle", t, func() {
+		helper := http.NewRequestHandlerHelper()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		Convey("should write encoded response", func() {
+			handler := http.NewRequestHandler(helper)
+
+			handler.Handle(func(context.Context, *native.Request) (interface{}, error) {
+				return map[string]string{"html": "<a>"}, nil
+			})(w, r)
+
+			So(w.Code, ShouldEqual, native.StatusOK)
+			So(w.Header().Get("Content-Type"), ShouldEqual, encoder.ApplicationJSON)
+			So(w.Body.String(), ShouldEqual, `{"html":"\u003ca\u003e"}`)
+		})
+		Convey("should use configured encoder factory", func() {
+			factory := encoder.NewFactory(encoder.WithJSONOptions(encoder.JSONEscapeHTML(false)))
+			handler := http.NewRequestHandler(helper, http.WithEncoderFactory(factory))
+
+			handler.Handle(func(context.Context, *native.Request) (interface{}, error) {
+				return map[string]string{"html": "<a>"}, nil
+			})(w, r)
+
+			So(w.Code, ShouldEqual, native.StatusOK)
+			So(w.Body.String(), ShouldEqual, `{"html":"<a>"}`)
+		})
+		Convey("should write error as bad request", func() {
+			handler := http.NewRequestHandler(helper)
+
+			handler.Handle(func(context.Context, *native.Request) (interface{}, error) {
+				return nil, errors.New("you are a teapot")
+			})(w, r)
+
+			So(w.Code, ShouldEqual, native.StatusBadRequest)
+			So(w.Body.String(), ShouldEqual, `"you are a teapot"`)
+		})
+		Convey("should compress response", func() {
+			handler := http.NewRequestHandler(helper, http.WithCompression(10))
+			r.Header.Set("Accept-Encoding", "br;q=0.5, gzip")
+
+			handler.Handle(func(context.Context, *native.Request) (interface{}, error) {
+				return strings.Repeat("a", 100), nil
+			})(w, r)
+
+			So(w.Code, ShouldEqual, native.StatusOK)
+			So(w.Header().Get("Content-Encoding"), ShouldEqual, compress.Gzip)
+			So(w.Header().Get("Vary"), ShouldEqual, "Accept-Encoding")
+			So(w.Header().Get("Content-Length"), ShouldEqual, strconv.Itoa(w.Body.Len()))
+
+			reader, err := compress.NewGzip().NewReader(w.Body)
+			So(err, ShouldBeNil)
+
+			actual, err := io.ReadAll(reader)
+			So(err, ShouldBeNil)
+			So(string(actual), ShouldEqual, `"`+strings.Repeat("a", 100)+`"`)
+		})
+		Convey("should not compress response when", func() {
+			Convey("it is below the minimum size", func() {
+				handler := http.NewRequestHandler(helper, http.WithCompression(1024))
+				r.Header.Set("Accept-Encoding", "gzip")
+
+				handler.Handle(func(context.Context, *native.Request) (interface{}, error) {
+					return "small", nil
+				})(w, r)
+
+				So(w.Header().Get("Content-Encoding"), ShouldBeEmpty)
+				So(w.Header().Get("Vary"), ShouldEqual, "Accept-Encoding")
+				So(w.Body.String(), ShouldEqual, `"small"`)
+			})
+			Convey("client does not accept a supported encoding", func() {
+				handler := http.NewRequestHandler(helper, http.WithCompression(0))
+				r.Header.Set("Accept-Encoding", "identity")
+
+				handler.Handle(func(context.Context, *native.Request) (interface{}, error) {
+					return "small", nil
+				})(w, r)
+
+				So(w.Header().Get("Content-Encoding"), ShouldBeEmpty)
+				So(w.Body.String(), ShouldEqual, `"small"`)
+			})
+			Convey("compression is not enabled", func() {
+				handler := http.NewRequestHandler(helper)
+				r.Header.Set("Accept-Encoding", "gzip")
+
+				handler.Handle(func(context.Context, *native.Request) (interface{}, error) {
+					return "small", nil
+				})(w, r)
+
+				So(w.Header().Get("Content-Encoding"), ShouldBeEmpty)
+				So(w.Header().Get("Vary"), ShouldBeEmpty)
+			})
+		})
+		Convey("should stream ndjson response", func() {
+			handler := http.NewRequestHandler(helper)
+			r.Header.Set("Accept", encoder.ApplicationNDJSON)
+
+			handler.Handle(func(context.Context, *native.Request) (interface{}, error) {
+				ch := make(chan int, 2)
+				ch <- 1
+				ch <- 2
+				close(ch)
+
+				return ch, nil
+			})(w, r)
+
+			So(w.Code, ShouldEqual, native.StatusOK)
+			So(w.Header().Get("Content-Type"), ShouldEqual, encoder.ApplicationNDJSON)
+			So(w.Body.String(), ShouldEqual, "1\n2\n")
+			So(w.Flushed, ShouldBeTrue)
+		})
+		Convey("should not block the producer when the client goes away", func() {
+			handler := http.NewRequestHandler(helper)
+			r.Header.Set("Accept", encoder.ApplicationNDJSON)
+			produced := make(chan int)
+
+			handler.Handle(func(context.Context, *native.Request) (interface{}, error) {
+				ch := make(chan int)
+
+				go func() {
+					defer close(ch)
+
+					sent := 0
+					for i := 0; i < 3; i++ {
+						ch <- i
+						sent++
+					}
+
+					produced <- sent
+				}()
+
+				return ch, nil
+			})(goneWriter{ResponseRecorder: w}, r)
+
+			So(<-produced, ShouldEqual, 3)
+			So(w.Body.String(), ShouldBeEmpty)
+		})
+	})
+}
+
+// goneWriter fails every write like the connection of a client that left.
+type goneWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (goneWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset by peer")
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	native "net/http"
+	"net/url"
+	"time"
+)
+
+// Response describes a finished exchange for callers that need more than the
+// decoded body, e.g. the status code, ETag, Link or rate limit headers.
+type Response struct {
+	StatusCode int
+	Header     native.Header
+	// Body is the value the response body was decoded into, it is left
+	// untouched for error statuses and HEAD requests.
+	Body interface{}
+	// Duration is the time from sending the request until the body was read.
+	Duration time.Duration
+	// URL is the url of the last request made, which differs from the one
+	// requested when redirects were followed.
+	URL *url.URL
+}
+
+func newResponse(resp *native.Response, req *native.Request, body interface{}, start time.Time) *Response {
+	finalURL := req.URL
+	if resp.Request != nil && resp.Request.URL != nil {
+		finalURL = resp.Request.URL
+	}
+
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		Duration:   time.Since(start),
+		URL:        finalURL,
+	}
+}
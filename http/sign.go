@@ -0,0 +1,457 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	native "net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kevinanthony/gorps/v2/header"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	hmacAlgorithm = "HMAC-SHA256"
+	// HMACDateHeader carries the signing time of HMACSigner requests.
+	HMACDateHeader = "X-Signature-Date"
+
+	signTimeFormat = "20060102T150405Z"
+	// DefaultMaxSkew is how far the signing time of a request may be from the
+	// verifier's clock.
+	DefaultMaxSkew = 5 * time.Minute
+)
+
+// ErrInvalidSignature is returned by verifiers for requests that are not
+// signed, or not signed correctly.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// errAuthorizationSet is returned by signers for requests that already carry
+// credentials in Authorization, which the signature would replace.
+var errAuthorizationSet = errors.New("authorization header is already set, use an authenticator that " +
+	"sends credentials in another header or the query")
+
+// Signer signs a request right before it is sent, after the Client resolved
+// the base url and added credentials, so the signature covers the request as
+// it goes out.
+//
+//go:generate mockery --name=Signer --structname=SignerMock --filename=signer_mock.go --inpackage
+type Signer interface {
+	Sign(req *native.Request) error
+}
+
+// Verifier checks the signature of an incoming request.
+//
+//go:generate mockery --name=Verifier --structname=VerifierMock --filename=verifier_mock.go --inpackage
+type Verifier interface {
+	Verify(req *native.Request) error
+}
+
+// SignOption configures signers and verifiers.
+type SignOption func(cfg *signConfig)
+
+type signConfig struct {
+	now          func() time.Time
+	headers      []string
+	maxSkew      time.Duration
+	sessionToken string
+}
+
+func newSignConfig(opts []SignOption) signConfig {
+	cfg := signConfig{
+		now:     time.Now,
+		maxSkew: DefaultMaxSkew,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// WithSigningClock replaces time.Now for signing and for checking the skew of
+// signed requests.
+func WithSigningClock(now func() time.Time) SignOption {
+	return func(cfg *signConfig) {
+		cfg.now = now
+	}
+}
+
+// WithSignedHeaders adds headers to the signature, besides the ones every
+// signer always includes.
+func WithSignedHeaders(headers ...string) SignOption {
+	return func(cfg *signConfig) {
+		cfg.headers = append(cfg.headers, headers...)
+	}
+}
+
+// WithMaxSkew sets how far the signing time of a request may be from the
+// verifier's clock, it defaults to DefaultMaxSkew.
+func WithMaxSkew(d time.Duration) SignOption {
+	return func(cfg *signConfig) {
+		cfg.maxSkew = d
+	}
+}
+
+// HMACSigner signs requests with a shared secret. The Authorization header
+// holds the key id, the signed headers and the hex HMAC-SHA256 of the
+// canonical request, which covers the method, path, sorted query, signed
+// headers and a hash of the body.
+type HMACSigner struct {
+	keyID  string
+	secret []byte
+	cfg    signConfig
+}
+
+func NewHMACSigner(keyID string, secret []byte, opts ...SignOption) *HMACSigner {
+	if len(secret) == 0 {
+		panic("signing secret is required")
+	}
+
+	return &HMACSigner{
+		keyID:  keyID,
+		secret: secret,
+		cfg:    newSignConfig(opts),
+	}
+}
+
+func (s *HMACSigner) Sign(req *native.Request) error {
+	if err := checkAuthorization(req, hmacAlgorithm); err != nil {
+		return err
+	}
+
+	payload, err := payloadHash(req, true)
+	if err != nil {
+		return err
+	}
+
+	date := s.cfg.now().UTC().Format(signTimeFormat)
+	req.Header.Set(HMACDateHeader, date)
+
+	signed := signedHeaders(req, append([]string{"host", HMACDateHeader}, s.cfg.headers...))
+	signature := hmacHex(s.secret, hmacStringToSign(date, canonicalRequest(req, signed, payload)))
+
+	req.Header.Set(header.Authorization, fmt.Sprintf("%s Credential=%s, SignedHeaders=%s, Signature=%s",
+		hmacAlgorithm, s.keyID, strings.Join(signed, ";"), signature))
+
+	return nil
+}
+
+// checkAuthorization fails when Authorization holds anything but an earlier
+// signature of algorithm, e.g. a bearer token.
+func checkAuthorization(req *native.Request, algorithm string) error {
+	value := req.Header.Get(header.Authorization)
+	if len(value) == 0 || strings.HasPrefix(value, algorithm+" ") {
+		return nil
+	}
+
+	return errAuthorizationSet
+}
+
+// HMACVerifier checks requests signed by an HMACSigner.
+type HMACVerifier struct {
+	secrets func(keyID string) ([]byte, bool)
+	cfg     signConfig
+}
+
+// NewHMACVerifier returns a verifier that looks up the secret of each key id
+// with secrets.
+func NewHMACVerifier(secrets func(keyID string) ([]byte, bool), opts ...SignOption) *HMACVerifier {
+	if secrets == nil {
+		panic("secret lookup is required")
+	}
+
+	return &HMACVerifier{
+		secrets: secrets,
+		cfg:     newSignConfig(opts),
+	}
+}
+
+func (v *HMACVerifier) Verify(req *native.Request) error {
+	auth, err := parseSignature(req, hmacAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	secret, ok := v.secrets(auth.credential)
+	if !ok {
+		return errors.Wrapf(ErrInvalidSignature, "unknown key %q", auth.credential)
+	}
+
+	date := req.Header.Get(HMACDateHeader)
+	if err := checkSkew(date, v.cfg); err != nil {
+		return err
+	}
+
+	payload, err := payloadHash(req, false)
+	if err != nil {
+		return err
+	}
+
+	expected := hmacHex(secret, hmacStringToSign(date, canonicalRequest(req, auth.signedHeaders, payload)))
+
+	return compareSignature(expected, auth.signature)
+}
+
+func hmacStringToSign(date, canonical string) string {
+	return strings.Join([]string{hmacAlgorithm, date, sha256Hex([]byte(canonical))}, "\n")
+}
+
+type signatureHeader struct {
+	credential    string
+	signedHeaders []string
+	signature     string
+}
+
+// parseSignature reads an Authorization header of the form
+// "<algorithm> Credential=..., SignedHeaders=..., Signature=...".
+func parseSignature(req *native.Request, algorithm string) (*signatureHeader, error) {
+	scheme, params, _ := strings.Cut(req.Header.Get(header.Authorization), " ")
+	if scheme != algorithm {
+		return nil, errors.Wrapf(ErrInvalidSignature, "expected %s authorization", algorithm)
+	}
+
+	auth := &signatureHeader{}
+
+	for _, param := range strings.Split(params, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+
+		switch key {
+		case "Credential":
+			auth.credential = value
+		case "SignedHeaders":
+			auth.signedHeaders = strings.Split(value, ";")
+		case "Signature":
+			auth.signature = value
+		}
+	}
+
+	if len(auth.credential) == 0 || len(auth.signedHeaders) == 0 || len(auth.signature) == 0 {
+		return nil, errors.Wrap(ErrInvalidSignature, "malformed authorization")
+	}
+
+	return auth, nil
+}
+
+func checkSkew(date string, cfg signConfig) error {
+	signedAt, err := time.Parse(signTimeFormat, date)
+	if err != nil {
+		return errors.Wrap(ErrInvalidSignature, "missing or malformed signing date")
+	}
+
+	if skew := cfg.now().Sub(signedAt); skew > cfg.maxSkew || skew < -cfg.maxSkew {
+		return errors.Wrap(ErrInvalidSignature, "signing date out of range")
+	}
+
+	return nil
+}
+
+func compareSignature(expected, actual string) error {
+	if !hmac.Equal([]byte(expected), []byte(actual)) {
+		return errors.Wrap(ErrInvalidSignature, "signature mismatch")
+	}
+
+	return nil
+}
+
+// canonicalRequest joins the parts of req covered by a signature:
+// method, path, query, headers, signed header names and payload hash.
+func canonicalRequest(req *native.Request, signed []string, payload string) string {
+	headers := make([]string, len(signed))
+	for i, name := range signed {
+		headers[i] = name + ":" + canonicalHeaderValue(req, name) + "\n"
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL),
+		canonicalQuery(req.URL),
+		strings.Join(headers, ""),
+		strings.Join(signed, ";"),
+		payload,
+	}, "\n")
+}
+
+func canonicalPath(u *url.URL) string {
+	if len(u.Path) == 0 {
+		return "/"
+	}
+
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func canonicalQuery(u *url.URL) string {
+	var pairs []string
+
+	for key, values := range u.Query() {
+		for _, value := range values {
+			pairs = append(pairs, uriEscape(key)+"="+uriEscape(value))
+		}
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "&")
+}
+
+func canonicalHeaderValue(req *native.Request, name string) string {
+	if name == "host" {
+		if len(req.Host) > 0 {
+			return req.Host
+		}
+
+		return req.URL.Host
+	}
+
+	values := append([]string(nil), req.Header.Values(name)...)
+	for i, value := range values {
+		values[i] = strings.Join(strings.Fields(value), " ")
+	}
+
+	return strings.Join(values, ",")
+}
+
+// signedHeaders returns the lower case names of the headers to sign that the
+// request has, sorted as the signature requires.
+func signedHeaders(req *native.Request, names []string) []string {
+	seen := map[string]bool{}
+	signed := make([]string, 0, len(names)+1)
+
+	if len(req.Header.Get(header.ContentType)) > 0 {
+		names = append(names, header.ContentType)
+	}
+
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if seen[name] || (name != "host" && len(req.Header.Values(name)) == 0) {
+			continue
+		}
+
+		seen[name] = true
+		signed = append(signed, name)
+	}
+
+	sort.Strings(signed)
+
+	return signed
+}
+
+// payloadHash returns the hex sha256 of the body, leaving the body in place
+// for whoever reads it next. Signers hash a copy from GetBody when there is
+// one, verifiers always hash the body that arrived.
+func payloadHash(req *native.Request, useGetBody bool) (string, error) {
+	if req.Body == nil || req.Body == native.NoBody {
+		return sha256Hex(nil), nil
+	}
+
+	var body io.ReadCloser
+
+	reopen := useGetBody && req.GetBody != nil
+	if reopen {
+		var err error
+
+		if body, err = req.GetBody(); err != nil {
+			return "", errors.Wrap(err, "sign: read body")
+		}
+	} else {
+		bts, err := io.ReadAll(req.Body)
+		if err != nil {
+			return "", errors.Wrap(err, "sign: read body")
+		}
+
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(bts))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(bts)), nil
+		}
+		body = io.NopCloser(bytes.NewReader(bts))
+	}
+
+	hash := sha256.New()
+	_, err := io.Copy(hash, body)
+
+	_ = body.Close()
+
+	if err != nil {
+		return "", errors.Wrap(err, "sign: read body")
+	}
+
+	if reopen {
+		// a seekable body reader is shared by every copy, so the body is
+		// reopened to rewind it past what was just hashed
+		fresh, err := req.GetBody()
+		if err != nil {
+			return "", errors.Wrap(err, "sign: read body")
+		}
+
+		_ = req.Body.Close()
+		req.Body = fresh
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// uriEscape escapes everything but the unreserved characters of RFC 3986.
+func uriEscape(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+
+			continue
+		}
+
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSum(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
+
+func hmacHex(key []byte, data string) string {
+	return hex.EncodeToString(hmacSum(key, data))
+}
+
+type signerKey struct{}
+
+// withSigner stores the signer of a single request for the Client to apply.
+func withSigner(ctx context.Context, signer Signer) context.Context {
+	return context.WithValue(ctx, signerKey{}, signer)
+}
+
+func signerFrom(ctx context.Context, fallback Signer) Signer {
+	if signer, ok := ctx.Value(signerKey{}).(Signer); ok {
+		return signer
+	}
+
+	return fallback
+}
@@ -0,0 +1,156 @@
+package http
+
+import (
+	"fmt"
+	native "net/http"
+	"strings"
+
+	"github.com/kevinanthony/gorps/v2/header"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sigV4Algorithm = "AWS4-HMAC-SHA256"
+	sigV4Request   = "aws4_request"
+	sigV4Date      = "20060102"
+
+	// SigV4DateHeader carries the signing time of SigV4 requests.
+	SigV4DateHeader = "X-Amz-Date"
+	// SigV4TokenHeader carries the session token of temporary credentials.
+	SigV4TokenHeader = "X-Amz-Security-Token"
+)
+
+// WithSessionToken sends the session token of temporary credentials with
+// SigV4 signed requests.
+func WithSessionToken(token string) SignOption {
+	return func(cfg *signConfig) {
+		cfg.sessionToken = token
+	}
+}
+
+// SigV4Signer signs requests with AWS Signature Version 4.
+type SigV4Signer struct {
+	accessKey string
+	secretKey string
+	region    string
+	service   string
+	cfg       signConfig
+}
+
+func NewSigV4Signer(accessKey, secretKey, region, service string, opts ...SignOption) *SigV4Signer {
+	if len(accessKey) == 0 || len(secretKey) == 0 {
+		panic("signing credentials are required")
+	}
+
+	return &SigV4Signer{
+		accessKey: accessKey,
+		secretKey: secretKey,
+		region:    region,
+		service:   service,
+		cfg:       newSignConfig(opts),
+	}
+}
+
+func (s *SigV4Signer) Sign(req *native.Request) error {
+	if err := checkAuthorization(req, sigV4Algorithm); err != nil {
+		return err
+	}
+
+	payload, err := payloadHash(req, true)
+	if err != nil {
+		return err
+	}
+
+	now := s.cfg.now().UTC()
+	date := now.Format(signTimeFormat)
+	req.Header.Set(SigV4DateHeader, date)
+
+	if len(s.cfg.sessionToken) > 0 {
+		req.Header.Set(SigV4TokenHeader, s.cfg.sessionToken)
+	}
+
+	scope := sigV4Scope(now.Format(sigV4Date), s.region, s.service)
+	signed := signedHeaders(req, append([]string{"host", SigV4DateHeader, SigV4TokenHeader}, s.cfg.headers...))
+	signature := sigV4Signature(s.secretKey, scope, date, canonicalRequest(req, signed, payload))
+
+	req.Header.Set(header.Authorization, fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.accessKey, scope, strings.Join(signed, ";"), signature))
+
+	return nil
+}
+
+// SigV4Verifier checks requests signed with AWS Signature Version 4 for a
+// region and service.
+type SigV4Verifier struct {
+	secrets func(accessKey string) (string, bool)
+	region  string
+	service string
+	cfg     signConfig
+}
+
+// NewSigV4Verifier returns a verifier that looks up the secret key of each
+// access key with secrets.
+func NewSigV4Verifier(secrets func(accessKey string) (string, bool), region, service string,
+	opts ...SignOption,
+) *SigV4Verifier {
+	if secrets == nil {
+		panic("secret lookup is required")
+	}
+
+	return &SigV4Verifier{
+		secrets: secrets,
+		region:  region,
+		service: service,
+		cfg:     newSignConfig(opts),
+	}
+}
+
+func (v *SigV4Verifier) Verify(req *native.Request) error {
+	auth, err := parseSignature(req, sigV4Algorithm)
+	if err != nil {
+		return err
+	}
+
+	accessKey, scope, _ := strings.Cut(auth.credential, "/")
+
+	secret, ok := v.secrets(accessKey)
+	if !ok {
+		return errors.Wrapf(ErrInvalidSignature, "unknown key %q", accessKey)
+	}
+
+	date := req.Header.Get(SigV4DateHeader)
+	if err := checkSkew(date, v.cfg); err != nil {
+		return err
+	}
+
+	if scope != sigV4Scope(date[:len(sigV4Date)], v.region, v.service) {
+		return errors.Wrapf(ErrInvalidSignature, "unexpected credential scope %q", scope)
+	}
+
+	payload, err := payloadHash(req, false)
+	if err != nil {
+		return err
+	}
+
+	expected := sigV4Signature(secret, scope, date, canonicalRequest(req, auth.signedHeaders, payload))
+
+	return compareSignature(expected, auth.signature)
+}
+
+func sigV4Scope(day, region, service string) string {
+	return strings.Join([]string{day, region, service, sigV4Request}, "/")
+}
+
+func sigV4Signature(secretKey, scope, date, canonical string) string {
+	parts := strings.Split(scope, "/")
+
+	key := []byte("AWS4" + secretKey)
+	for _, part := range parts {
+		key = hmacSum(key, part)
+	}
+
+	stringToSign := strings.Join([]string{sigV4Algorithm, date, scope, sha256Hex([]byte(canonical))}, "\n")
+
+	return hmacHex(key, stringToSign)
+}
@@ -0,0 +1,100 @@
+package http_test
+
+import (
+	native "net/http"
+	"testing"
+	"time"
+
+	"github.com/kevinanthony/gorps/v2/http"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSigV4Signer(t *testing.T) {
+	t.Parallel()
+
+	Convey("SigV4Signer", t, func() {
+		// the example request from the AWS Signature Version 4 documentation
+		clock := func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+		secret := "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+
+		newRequest := func() *native.Request {
+			req, err := native.NewRequest(native.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+			So(err, ShouldBeNil)
+
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+			return req
+		}
+
+		Convey("should match the documented signature", func() {
+			req := newRequest()
+
+			err := http.NewSigV4Signer("AKIDEXAMPLE", secret, "us-east-1", "iam", http.WithSigningClock(clock)).Sign(req)
+
+			So(err, ShouldBeNil)
+			So(req.Header.Get(http.SigV4DateHeader), ShouldEqual, "20150830T123600Z")
+			So(req.Header.Get("Authorization"), ShouldEqual, "AWS4-HMAC-SHA256 "+
+				"Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, "+
+				"SignedHeaders=content-type;host;x-amz-date, "+
+				"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7")
+		})
+		Convey("should sign the session token", func() {
+			req := newRequest()
+
+			err := http.NewSigV4Signer("AKIDEXAMPLE", secret, "us-east-1", "iam",
+				http.WithSigningClock(clock), http.WithSessionToken("session")).Sign(req)
+
+			So(err, ShouldBeNil)
+			So(req.Header.Get(http.SigV4TokenHeader), ShouldEqual, "session")
+			So(req.Header.Get("Authorization"), ShouldContainSubstring,
+				"SignedHeaders=content-type;host;x-amz-date;x-amz-security-token,")
+		})
+		Convey("should not replace credentials in Authorization", func() {
+			req := newRequest()
+			req.Header.Set("Authorization", "Bearer token")
+
+			err := http.NewSigV4Signer("AKIDEXAMPLE", secret, "us-east-1", "iam").Sign(req)
+
+			So(err, ShouldNotBeNil)
+			So(req.Header.Get("Authorization"), ShouldEqual, "Bearer token")
+		})
+		Convey("verifier", func() {
+			secrets := func(accessKey string) (string, bool) {
+				return secret, accessKey == "AKIDEXAMPLE"
+			}
+			signer := http.NewSigV4Signer("AKIDEXAMPLE", secret, "us-east-1", "iam", http.WithSigningClock(clock))
+
+			Convey("should accept signed requests", func() {
+				req := newRequest()
+				So(signer.Sign(req), ShouldBeNil)
+
+				verifier := http.NewSigV4Verifier(secrets, "us-east-1", "iam", http.WithSigningClock(clock))
+
+				So(verifier.Verify(req), ShouldBeNil)
+			})
+			Convey("should refuse requests for another region", func() {
+				req := newRequest()
+				So(signer.Sign(req), ShouldBeNil)
+
+				verifier := http.NewSigV4Verifier(secrets, "eu-west-1", "iam", http.WithSigningClock(clock))
+
+				So(verifier.Verify(req), ShouldBeError,
+					`unexpected credential scope "20150830/us-east-1/iam/aws4_request": invalid signature`)
+			})
+			Convey("should refuse requests changed after signing", func() {
+				req := newRequest()
+				So(signer.Sign(req), ShouldBeNil)
+
+				req.URL.RawQuery = "Action=DeleteUser&Version=2010-05-08"
+				verifier := http.NewSigV4Verifier(secrets, "us-east-1", "iam", http.WithSigningClock(clock))
+
+				So(verifier.Verify(req), ShouldBeError, "signature mismatch: invalid signature")
+			})
+		})
+		Convey("should panic when credentials are missing", func() {
+			So(func() { http.NewSigV4Signer("", secret, "us-east-1", "iam") }, ShouldPanicWith,
+				"signing credentials are required")
+		})
+	})
+}
@@ -0,0 +1,206 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	native "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevinanthony/gorps/v2/encoder"
+	"github.com/kevinanthony/gorps/v2/http"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHMACSigner(t *testing.T) {
+	t.Parallel()
+
+	Convey("HMACSigner", t, func() {
+		now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		clock := func() time.Time { return now }
+		secrets := func(keyID string) ([]byte, bool) {
+			return []byte("secret"), keyID == "key-1"
+		}
+
+		signer := http.NewHMACSigner("key-1", []byte("secret"), http.WithSigningClock(clock),
+			http.WithSignedHeaders("X-Tenant"))
+		verifier := http.NewHMACVerifier(secrets, http.WithSigningClock(clock))
+
+		signed := func() *native.Request {
+			req, err := native.NewRequest(native.MethodPost, "https://test.com/users/a b?b=2&a=1",
+				strings.NewReader(`{"name":"bob"}`))
+			So(err, ShouldBeNil)
+
+			req.Header.Set("Content-Type", encoder.ApplicationJSON)
+			req.Header.Set("X-Tenant", "acme")
+			So(signer.Sign(req), ShouldBeNil)
+
+			return req
+		}
+
+		Convey("should set date and authorization headers", func() {
+			req := signed()
+
+			So(req.Header.Get(http.HMACDateHeader), ShouldEqual, "20240102T030405Z")
+			So(req.Header.Get("Authorization"), ShouldStartWith,
+				"HMAC-SHA256 Credential=key-1, SignedHeaders=content-type;host;x-signature-date;x-tenant, Signature=")
+		})
+		Convey("should replace its own earlier signature", func() {
+			req := signed()
+
+			So(signer.Sign(req), ShouldBeNil)
+			So(verifier.Verify(req), ShouldBeNil)
+		})
+		Convey("should leave the body readable", func() {
+			bts, err := io.ReadAll(signed().Body)
+
+			So(err, ShouldBeNil)
+			So(string(bts), ShouldEqual, `{"name":"bob"}`)
+		})
+		Convey("should be accepted by the verifier", func() {
+			So(verifier.Verify(signed()), ShouldBeNil)
+		})
+		Convey("should be refused by the verifier when", func() {
+			for name, tc := range map[string]struct {
+				change   func(req *native.Request)
+				expected string
+			}{
+				"the body changed": {
+					change:   func(req *native.Request) { req.Body = io.NopCloser(strings.NewReader(`{"name":"eve"}`)) },
+					expected: "signature mismatch: invalid signature",
+				},
+				"the query changed": {
+					change:   func(req *native.Request) { req.URL.RawQuery = "a=1&b=3" },
+					expected: "signature mismatch: invalid signature",
+				},
+				"a signed header changed": {
+					change:   func(req *native.Request) { req.Header.Set("X-Tenant", "other") },
+					expected: "signature mismatch: invalid signature",
+				},
+				"the key is unknown": {
+					change: func(req *native.Request) {
+						req.Header.Set("Authorization", strings.Replace(req.Header.Get("Authorization"), "key-1", "key-2", 1))
+					},
+					expected: `unknown key "key-2": invalid signature`,
+				},
+				"it is not signed": {
+					change:   func(req *native.Request) { req.Header.Del("Authorization") },
+					expected: "expected HMAC-SHA256 authorization: invalid signature",
+				},
+				"it was signed too long ago": {
+					change:   func(req *native.Request) { req.Header.Set(http.HMACDateHeader, "20240102T025405Z") },
+					expected: "signing date out of range: invalid signature",
+				},
+			} {
+				Convey(name, func() {
+					req := signed()
+					tc.change(req)
+
+					err := verifier.Verify(req)
+
+					So(err, ShouldBeError, tc.expected)
+					So(errors.Is(err, http.ErrInvalidSignature), ShouldBeTrue)
+				})
+			}
+		})
+		Convey("should panic when secret is empty", func() {
+			So(func() { http.NewHMACSigner("key", nil) }, ShouldPanicWith, "signing secret is required")
+		})
+	})
+}
+
+func TestSign_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	Convey("signed requests", t, func() {
+		ctx := context.Background()
+		secrets := func(string) ([]byte, bool) { return []byte("secret"), true }
+
+		handler := http.NewRequestHandler(http.NewRequestHandlerHelper(),
+			http.WithVerifier(http.NewHMACVerifier(secrets)))
+		server := httptest.NewServer(handler.Handle(func(_ context.Context, r *native.Request) (interface{}, error) {
+			bts, err := io.ReadAll(r.Body)
+
+			return string(bts), err
+		}))
+
+		Reset(server.Close)
+
+		Convey("should reach the handler with their body when the signature is valid", func() {
+			c := http.NewClient(server.Client(), encoder.NewFactory(),
+				http.WithSigner(http.NewHMACSigner("key", []byte("secret"))))
+
+			var actual string
+
+			err := http.NewRequest(c).Post().URL(server.URL+"/users").Query("q", "a b").
+				JSON(map[string]int{"a": 1}).DoAndUnmarshal(ctx, &actual)
+
+			So(err, ShouldBeNil)
+			So(actual, ShouldEqual, `{"a":1}`)
+		})
+		Convey("should send a seekable body reader in full", func() {
+			c := http.NewClient(server.Client(), encoder.NewFactory(),
+				http.WithSigner(http.NewHMACSigner("key", []byte("secret"))))
+
+			var actual string
+
+			err := http.NewRequest(c).Put().URL(server.URL+"/users").
+				BodyReader(bytes.NewReader([]byte(`{"a":1}`))).DoAndUnmarshal(ctx, &actual)
+
+			So(err, ShouldBeNil)
+			So(actual, ShouldEqual, `{"a":1}`)
+		})
+		Convey("should be refused with 401 when the signature is wrong", func() {
+			c := http.NewClient(server.Client(), encoder.NewFactory())
+
+			err := http.NewRequest(c).Post().URL(server.URL).
+				Sign(http.NewHMACSigner("key", []byte("wrong"))).DoAndUnmarshal(ctx, &struct{}{})
+
+			So(err, ShouldBeError, "401: signature mismatch: invalid signature: bad requestBroker")
+		})
+		Convey("should return error when signing fails", func() {
+			signerMock := &http.SignerMock{}
+			signerMock.On("Sign", mock.Anything).Return(errors.New("no key")).Once()
+
+			c := http.NewClient(server.Client(), encoder.NewFactory(), http.WithSigner(signerMock))
+
+			err := http.NewRequest(c).URL(server.URL).DoAndUnmarshal(ctx, &struct{}{})
+
+			So(err, ShouldBeError, "sign: no key")
+		})
+		Convey("should return error when the signature would replace credentials", func() {
+			c := http.NewClient(server.Client(), encoder.NewFactory(), http.WithAuth(http.BearerToken("token")),
+				http.WithSigner(http.NewHMACSigner("key", []byte("secret"))))
+
+			err := http.NewRequest(c).URL(server.URL).DoAndUnmarshal(ctx, &struct{}{})
+
+			So(err, ShouldBeError, "sign: authorization header is already set, use an authenticator that "+
+				"sends credentials in another header or the query")
+
+			err = http.NewRequest(http.NewClient(server.Client(), encoder.NewFactory())).URL(server.URL).
+				Auth(http.BearerToken("token")).Sign(http.NewHMACSigner("key", []byte("secret"))).
+				DoAndUnmarshal(ctx, &struct{}{})
+
+			So(err, ShouldNotBeNil)
+		})
+		Convey("should cover credentials sent in a signed header", func() {
+			c := http.NewClient(server.Client(), encoder.NewFactory(), http.WithAuth(http.APIKeyHeader("X-Api-Key", "k")),
+				http.WithSigner(http.NewHMACSigner("key", []byte("secret"), http.WithSignedHeaders("X-Api-Key"))))
+
+			var actual string
+
+			So(http.NewRequest(c).URL(server.URL).DoAndUnmarshal(ctx, &actual), ShouldBeNil)
+		})
+		Convey("should return error when signer is nil", func() {
+			_, err := http.NewRequest(&http.ClientMock{}).URL(server.URL).Sign(nil).CreateRequest(ctx)
+
+			So(err, ShouldBeError, "signer is nil")
+		})
+	})
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TimeoutPhase is the part of an exchange that was still running when a
+// timeout expired.
+type TimeoutPhase string
+
+const (
+	// PhaseConnect means no connection to the server was made in time.
+	PhaseConnect TimeoutPhase = "connect"
+	// PhaseResponseHeader means the server accepted the request but did not
+	// send the response headers in time.
+	PhaseResponseHeader TimeoutPhase = "response header"
+	// PhaseBody means the response headers arrived but the body did not.
+	PhaseBody TimeoutPhase = "body read"
+)
+
+// TimeoutError is returned when a request set with Timeout, or a Client
+// created WithTimeout, runs out of time. It matches context.DeadlineExceeded
+// with errors.Is.
+type TimeoutError struct {
+	Phase TimeoutPhase
+	Limit time.Duration
+	Err   error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timeout after %s", e.Phase, e.Limit)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TimeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// Timeout reports true, so TimeoutError satisfies net.Error style checks.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+type timeoutKey struct{}
+
+// withTimeout stores the timeout of a single request for the Client to apply.
+func withTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, d)
+}
+
+func timeoutFrom(ctx context.Context, fallback time.Duration) time.Duration {
+	if d, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		return d
+	}
+
+	return fallback
+}
+
+// phaseTracker follows a request through the transport so a timeout can be
+// blamed on the phase that was slow.
+type phaseTracker struct {
+	ctx       context.Context
+	limit     time.Duration
+	deadline  time.Time
+	connected atomic.Bool
+}
+
+// newPhaseTracker follows a request whose ctx was given deadline by the
+// timeout of limit.
+func newPhaseTracker(ctx context.Context, limit time.Duration, deadline time.Time) (*phaseTracker, context.Context) {
+	t := &phaseTracker{limit: limit, deadline: deadline}
+	t.ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) {
+			t.connected.Store(true)
+		},
+	})
+
+	return t, t.ctx
+}
+
+// wrap turns err into a TimeoutError when it was caused by the timeout. An
+// earlier deadline of the caller's context is left for the caller to report.
+func (t *phaseTracker) wrap(err error, phase TimeoutPhase) error {
+	if err == nil || !errors.Is(t.ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+
+	if deadline, _ := t.ctx.Deadline(); !deadline.Equal(t.deadline) {
+		return err
+	}
+
+	if phase != PhaseBody {
+		phase = PhaseConnect
+		if t.connected.Load() {
+			phase = PhaseResponseHeader
+		}
+	}
+
+	return &TimeoutError{Phase: phase, Limit: t.limit, Err: err}
+}
+
+// timeoutBody reports body read timeouts and releases the timeout once the
+// body is closed.
+type timeoutBody struct {
+	io.ReadCloser
+	tracker *phaseTracker
+	cancel  context.CancelFunc
+}
+
+func (b timeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if errors.Is(err, io.EOF) {
+		return n, err
+	}
+
+	return n, b.tracker.wrap(err, PhaseBody)
+}
+
+func (b timeoutBody) Close() error {
+	defer b.cancel()
+
+	return b.ReadCloser.Close()
+}